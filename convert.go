@@ -0,0 +1,117 @@
+package yamlwalker
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// FromValue converts Go value to the tree of nodes.
+// Structs, maps and slices are converted the same way yaml.Marshal() does,
+// i.e. yaml struct tags are honoured and keys of Go maps are sorted.
+func FromValue(value interface{}) (*YamlWalker, error) {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	return NewYamlWalker().decode(&node, newDecodeState())
+}
+
+// Decode decodes the node specified by path into out the same way yaml.Unmarshal() does,
+// i.e. yaml struct tags are honoured.
+// If path does not exists it returns ErrNotFound.
+func (walker *YamlWalker) Decode(path string, out interface{}) error {
+	node, err := walker.Get(path)
+	if err != nil {
+		return err
+	}
+
+	n, err := node.encode()
+	if err != nil {
+		return err
+	}
+	return n.Decode(out)
+}
+
+// Assign converts Go value with FromValue() and stores it at the path.
+// Unlike Set() the existing nodes are updated in place:
+// keys of maps keep their order, style and comments, new keys are appended at the end
+// and keys missing in the value are deleted; items of sequences are updated by index;
+// scalars keep their style unless the type of the value changes.
+// If path does not exists it returns ErrNotFound.
+func (walker *YamlWalker) Assign(path string, value interface{}) error {
+	node, err := walker.Get(path)
+	if err != nil {
+		return err
+	}
+
+	src, err := FromValue(value)
+	if err != nil {
+		return err
+	}
+
+	node.assign(src)
+	return nil
+}
+
+func (walker *YamlWalker) assign(src *YamlWalker) {
+	if walker.alias != nil {
+		walker.replaceWith(src)
+		return
+	}
+
+	switch x := src.data.(type) {
+	case map[string]*YamlWalker:
+		existing, ok := walker.data.(map[string]*YamlWalker)
+		if !ok {
+			walker.replaceWith(src)
+			return
+		}
+		keys := make([]yamlKey, 0, len(src.keys))
+		data := make(map[string]*YamlWalker, len(x))
+		for _, k := range walker.keys {
+			if value, found := x[k.name]; found {
+				existing[k.name].assign(value)
+				keys = append(keys, k)
+				data[k.name] = existing[k.name]
+			}
+		}
+		for _, k := range src.keys {
+			if _, found := data[k.name]; !found {
+				keys = append(keys, k)
+				data[k.name] = x[k.name]
+			}
+		}
+		walker.keys = keys
+		walker.data = data
+	case []*YamlWalker:
+		existing, ok := walker.data.([]*YamlWalker)
+		if !ok {
+			walker.replaceWith(src)
+			return
+		}
+		items := make([]*YamlWalker, len(x))
+		for i := range x {
+			if i < len(existing) {
+				existing[i].assign(x[i])
+				items[i] = existing[i]
+			} else {
+				items[i] = x[i]
+			}
+		}
+		walker.data = items
+	default:
+		switch walker.data.(type) {
+		case map[string]*YamlWalker, []*YamlWalker:
+			walker.replaceWith(src)
+			return
+		}
+		if walker.tag == src.tag && walker.data == src.data {
+			return
+		}
+		if walker.tag != src.tag {
+			walker.style = src.style
+		}
+		walker.data = src.data
+		walker.tag = src.tag
+		walker.raw = src.raw
+	}
+}
@@ -2,31 +2,74 @@ package yamlwalker
 
 import (
 	"fmt"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-func (walker *YamlWalker) decode(node *yaml.Node) (*YamlWalker, error) {
+// decodeState keeps the data shared by all nodes of a single document
+type decodeState struct {
+	anchors    map[*yaml.Node]*YamlWalker
+	open       map[*YamlWalker]bool // maps and sequences being decoded, an alias of one of them is recursive
+	duplicates DuplicateKeyPolicy
+}
+
+func newDecodeState() *decodeState {
+	return &decodeState{
+		anchors: make(map[*yaml.Node]*YamlWalker),
+		open:    make(map[*YamlWalker]bool),
+	}
+}
+
+func (walker *YamlWalker) decode(node *yaml.Node, state *decodeState) (*YamlWalker, error) {
 	log(fmt.Sprintf("+++++++++++++++++++++++\n%s", printNodeContent(node)))
 
 	newYW := NewYamlWalker()
 	newYW.style = node.Style
+	newYW.anchor = node.Anchor
+	newYW.position = Position{Line: node.Line, Column: node.Column}
+	newYW.comment = Comment{
+		Head: node.HeadComment,
+		Line: node.LineComment,
+		Foot: node.FootComment,
+	}
+	if len(node.Anchor) > 0 {
+		state.anchors[node] = newYW
+	}
 
 	switch node.Kind {
 	case yaml.MappingNode:
 		var err error
-		newYW.keys, newYW.data, err = walker.decodeMap(node)
+		state.open[newYW] = true
+		newYW.keys, newYW.data, err = walker.decodeMap(node, state)
 		if err != nil {
 			return nil, err
 		}
+		delete(state.open, newYW)
 	case yaml.SequenceNode:
 		var err error
-		newYW.data, err = walker.decodeSeq(node)
+		state.open[newYW] = true
+		newYW.data, err = walker.decodeSeq(node, state)
 		if err != nil {
 			return nil, err
 		}
+		delete(state.open, newYW)
 	case yaml.ScalarNode:
 		newYW.data = walker.decodeScalar(node)
+		newYW.tag = node.Tag
+		raw := node.Value
+		newYW.raw = &raw
+	case yaml.AliasNode:
+		target, found := state.anchors[node.Alias]
+		if !found {
+			err := fmt.Errorf("line %d: unknown anchor '%s' referenced", node.Line, node.Value)
+			return nil, err
+		}
+		if state.open[target] {
+			err := fmt.Errorf("line %d: recursive alias '%s' referenced", node.Line, node.Value)
+			return nil, err
+		}
+		newYW.alias = target
 	default:
 		err := fmt.Errorf("line %d: unsupported node kind %d(%s)", node.Line, node.Kind, decodeKind(node.Kind))
 		return nil, err
@@ -37,27 +80,56 @@ func (walker *YamlWalker) decode(node *yaml.Node) (*YamlWalker, error) {
 	return newYW, nil
 }
 
-func (walker *YamlWalker) decodeMap(node *yaml.Node) (keys []yamlKey, data map[string]*YamlWalker, err error) {
+func (walker *YamlWalker) decodeMap(node *yaml.Node, state *decodeState) (keys []yamlKey, data map[string]*YamlWalker, err error) {
 	count := len(node.Content) / 2
 
-	keys = make([]yamlKey, count)
+	keys = make([]yamlKey, 0, count)
 	data = make(map[string]*YamlWalker)
 
 	for i := 0; i < count; i++ {
 		contentIdx := i * 2
 		contentKey := node.Content[contentIdx]
-		keyName := contentKey.Value
 		keyStyle := contentKey.Style
+		key, e := walker.decodeKey(contentKey, state)
+		if e != nil {
+			err = e
+			return
+		}
+		key.style = keyStyle
+		key.position = Position{Line: contentKey.Line, Column: contentKey.Column}
+		key.comment = Comment{
+			Head: contentKey.HeadComment,
+			Line: contentKey.LineComment,
+			Foot: contentKey.FootComment,
+		}
+		if j := keyIndex(keys, key.name); j >= 0 && keys[j].tag() != key.tag() {
+			// keys of different types written the same way, e.g. 1 and "1", the string one keeps the name
+			if key.node == nil {
+				data[keys[j].qualifiedName()] = data[key.name]
+				delete(data, key.name)
+				keys[j].name = keys[j].qualifiedName()
+			} else {
+				key.name = key.qualifiedName()
+			}
+		}
+		keyName := key.name
+
 		contentValue := node.Content[contentIdx+1]
-		value, e := NewYamlWalker().decode(contentValue)
+		value, e := NewYamlWalker().decode(contentValue, state)
 		if e != nil {
 			err = e
 			return
 		}
-		keys[i] = yamlKey{
-			style: keyStyle,
-			name:  keyName,
+
+		if _, found := data[keyName]; found {
+			keys, err = decodeDuplicate(keys, key, value, data, state.duplicates)
+			if err != nil {
+				return
+			}
+			continue
 		}
+
+		keys = append(keys, key)
 		data[keyName] = value
 
 		log(fmt.Sprintf("=    map[%s]=%v\n", keyName, value.Value()))
@@ -66,10 +138,71 @@ func (walker *YamlWalker) decodeMap(node *yaml.Node) (keys []yamlKey, data map[s
 	return
 }
 
-func (walker *YamlWalker) decodeSeq(node *yaml.Node) ([]*YamlWalker, error) {
+// decodeKey decodes the key of a map.
+// String keys and the merge key "<<" are kept by name only,
+// other keys keep the decoded node to be written back as they are.
+// The name of a mapping or sequence key is its flow style YAML, e.g. "[a, b]".
+func (walker *YamlWalker) decodeKey(node *yaml.Node, state *decodeState) (key yamlKey, err error) {
+	key.name = node.Value
+	if node.Kind == yaml.ScalarNode && len(node.Anchor) == 0 {
+		switch node.ShortTag() {
+		case "!!str", "!!merge":
+			return
+		}
+	}
+
+	key.node, err = NewYamlWalker().decode(node, state)
+	if err != nil {
+		return
+	}
+
+	target := key.node.resolve()
+	switch {
+	case target.raw != nil:
+		key.name = *target.raw
+	case target.isScalar():
+		key.name, _ = encodeValue(target.data)
+	default:
+		key.name, err = flowText(target)
+	}
+	return
+}
+
+// decodeDuplicate applies the policy to the repeated key and returns the updated keys
+func decodeDuplicate(keys []yamlKey, key yamlKey, value *YamlWalker, data map[string]*YamlWalker, policy DuplicateKeyPolicy) ([]yamlKey, error) {
+	first := 0
+	for i := range keys {
+		if keys[i].name == key.name {
+			first = i
+			break
+		}
+	}
+
+	switch policy {
+	case DuplicateKeyFirstWins:
+	case DuplicateKeyLastWins:
+		keys = append(keys[:first], keys[first+1:]...)
+		keys = append(keys, key)
+		data[key.name] = value
+	case DuplicateKeyKeepAll:
+		// the repeated key belongs to the first key of the same name and keeps its index among all keys
+		index := len(keys)
+		for _, k := range keys {
+			index += len(k.duplicates)
+		}
+		keys[first].duplicates = append(keys[first].duplicates, duplicate{key: key, node: value, index: index})
+	default:
+		return nil, fmt.Errorf("line %d: %w '%s', first defined at line %d",
+			key.position.Line, ErrDuplicateKey, key.name, keys[first].position.Line)
+	}
+
+	return keys, nil
+}
+
+func (walker *YamlWalker) decodeSeq(node *yaml.Node, state *decodeState) ([]*YamlWalker, error) {
 	slice := make([]*YamlWalker, len(node.Content))
 	for i, v := range node.Content {
-		sibling, err := NewYamlWalker().decode(v)
+		sibling, err := NewYamlWalker().decode(v, state)
 		if err != nil {
 			return nil, err
 		}
@@ -81,7 +214,21 @@ func (walker *YamlWalker) decodeSeq(node *yaml.Node) ([]*YamlWalker, error) {
 	return slice, nil
 }
 
+// decodeScalar converts the scalar to the type of its resolved tag.
+// Values of unknown or custom tags are kept as strings.
 func (walker *YamlWalker) decodeScalar(node *yaml.Node) interface{} {
+	switch node.ShortTag() {
+	case "!!int", "!!float", "!!bool", "!!null":
+		var value interface{}
+		if err := node.Decode(&value); err == nil {
+			return value
+		}
+	case "!!timestamp":
+		var value time.Time
+		if err := node.Decode(&value); err == nil {
+			return value
+		}
+	}
 	return node.Value
 }
 
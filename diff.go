@@ -0,0 +1,220 @@
+package yamlwalker
+
+import (
+	"strconv"
+)
+
+// ChangeOp is the kind of the change reported by Diff()
+type ChangeOp int
+
+const (
+	// ChangeAdded means the node is present in the new tree only
+	ChangeAdded ChangeOp = iota
+	// ChangeRemoved means the node is present in the old tree only
+	ChangeRemoved
+	// ChangeModified means the value of the node differs
+	ChangeModified
+	// ChangeMoved means the node changed its position
+	ChangeMoved
+)
+
+func (op ChangeOp) String() string {
+	switch op {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	case ChangeMoved:
+		return "moved"
+	}
+	return "unknown"
+}
+
+// Change describes a single difference between two trees.
+// Old is <nil> for added nodes and New is <nil> for removed ones.
+// From is the path of the node in the old tree, it differs from Path for moved items of sequences.
+type Change struct {
+	Path Path
+	From Path
+	Op   ChangeOp
+	Old  *YamlWalker
+	New  *YamlWalker
+}
+
+// DiffOption configures Diff()
+type DiffOption func(*diffOptions)
+
+type diffOptions struct {
+	keys   []sequenceRule
+	format bool
+	err    error // the first invalid pattern
+}
+
+// WithDiffKey matches items of the sequence at the path by the value of the key field instead of the index.
+// The path is a pattern, element "*" matches any key or index, e.g. "services.*.ports".
+// Invalid pattern makes Diff() fail with ErrInvalidPath.
+func WithDiffKey(path string, key string) DiffOption {
+	return func(o *diffOptions) {
+		pattern, err := parsePattern(path)
+		if err != nil {
+			if o.err == nil {
+				o.err = err
+			}
+			return
+		}
+		o.keys = append(o.keys, sequenceRule{pattern: pattern, strategy: SequenceMergeByKey, key: key})
+	}
+}
+
+// WithFormatChanges reports changes which do not affect the value:
+// changed style or tag of the node are reported as modified
+// and keys of map which changed their order are reported as moved.
+func WithFormatChanges() DiffOption {
+	return func(o *diffOptions) {
+		o.format = true
+	}
+}
+
+// Diff returns the changes turning the tree a into the tree b.
+//
+// Maps are compared key by key, items of sequences are compared by index
+// unless the sequence is matched by key, see WithDiffKey().
+// Items matched by key which changed the index are reported as moved,
+// their nested changes have paths of the new tree.
+// Aliases are resolved, i.e. the values are compared.
+// Maps are compared with the keys merged by the merge key "<<", the merge key itself is not reported.
+// Old and New of the changes are the nodes of the trees, not copies.
+// If the pattern of an option is invalid err wraps ErrInvalidPath.
+func Diff(a, b *YamlWalker, opts ...DiffOption) ([]Change, error) {
+	options := &diffOptions{}
+	for _, o := range opts {
+		o(options)
+	}
+	if options.err != nil {
+		return nil, options.err
+	}
+
+	changes := []Change{}
+	diffNodes(a, b, Path{}, Path{}, options, &changes)
+	return changes, nil
+}
+
+func diffNodes(a, b *YamlWalker, from, path Path, options *diffOptions, changes *[]Change) {
+	x, y := a.resolve(), b.resolve()
+	switch xv := x.data.(type) {
+	case map[string]*YamlWalker:
+		if _, ok := y.data.(map[string]*YamlWalker); ok {
+			diffFormat(x, y, from, path, options, changes)
+			diffMaps(x, y, from, path, options, changes)
+			return
+		}
+	case []*YamlWalker:
+		if yv, ok := y.data.([]*YamlWalker); ok {
+			diffFormat(x, y, from, path, options, changes)
+			if rule, ok := matchRule(options.keys, path); ok {
+				diffSeqByKey(xv, yv, rule.key, from, path, options, changes)
+			} else {
+				diffSeqByIndex(xv, yv, from, path, options, changes)
+			}
+			return
+		}
+	}
+
+	if !x.equal(y, sameValue) {
+		*changes = append(*changes, Change{Path: path, From: from, Op: ChangeModified, Old: a, New: b})
+		return
+	}
+	diffFormat(x, y, from, path, options, changes)
+}
+
+// diffFormat reports the change of style or tag of the nodes having equal values
+func diffFormat(x, y *YamlWalker, from, path Path, options *diffOptions, changes *[]Change) {
+	if options.format && (x.style != y.style || x.tag != y.tag) {
+		*changes = append(*changes, Change{Path: path, From: from, Op: ChangeModified, Old: x, New: y})
+	}
+}
+
+// diffMaps compares the maps with the keys merged by the merge key "<<" added
+func diffMaps(a, b *YamlWalker, from, path Path, options *diffOptions, changes *[]Change) {
+	aKeys, x := a.merged(nil)
+	bKeys, y := b.merged(nil)
+	for _, k := range aKeys {
+		if _, found := y[k.name]; !found {
+			*changes = append(*changes, Change{Path: path.Append(k.name), From: from.Append(k.name), Op: ChangeRemoved, Old: x[k.name]})
+		}
+	}
+
+	common := 0
+	keys := make(map[string]yamlKey, len(aKeys))
+	order := make(map[string]int, len(aKeys))
+	for _, k := range aKeys {
+		if _, found := y[k.name]; found {
+			keys[k.name] = k
+			order[k.name] = common
+			common++
+		}
+	}
+
+	index := 0
+	for _, k := range bKeys {
+		value := y[k.name]
+		old, found := x[k.name]
+		if !found {
+			*changes = append(*changes, Change{Path: path.Append(k.name), Op: ChangeAdded, New: value})
+			continue
+		}
+
+		if options.format {
+			if order[k.name] != index {
+				*changes = append(*changes, Change{Path: path.Append(k.name), From: from.Append(k.name), Op: ChangeMoved, Old: old, New: value})
+			}
+			if keys[k.name].style != k.style {
+				*changes = append(*changes, Change{Path: path.Append(k.name), From: from.Append(k.name), Op: ChangeModified, Old: old, New: value})
+			}
+		}
+		index++
+		diffNodes(old, value, from.Append(k.name), path.Append(k.name), options, changes)
+	}
+}
+
+func diffSeqByIndex(x, y []*YamlWalker, from, path Path, options *diffOptions, changes *[]Change) {
+	for i := range y {
+		p := path.Append(strconv.Itoa(i))
+		if i >= len(x) {
+			*changes = append(*changes, Change{Path: p, Op: ChangeAdded, New: y[i]})
+			continue
+		}
+		diffNodes(x[i], y[i], from.Append(strconv.Itoa(i)), p, options, changes)
+	}
+	for i := len(y); i < len(x); i++ {
+		p := from.Append(strconv.Itoa(i))
+		*changes = append(*changes, Change{Path: p, From: p, Op: ChangeRemoved, Old: x[i]})
+	}
+}
+
+func diffSeqByKey(x, y []*YamlWalker, key string, from, path Path, options *diffOptions, changes *[]Change) {
+	matched := make(map[int]bool, len(x))
+	for i, v := range y {
+		p := path.Append(strconv.Itoa(i))
+		j := findByKey(x, v, key)
+		if j < 0 || matched[j] {
+			*changes = append(*changes, Change{Path: p, Op: ChangeAdded, New: v})
+			continue
+		}
+		matched[j] = true
+
+		f := from.Append(strconv.Itoa(j))
+		if i != j {
+			*changes = append(*changes, Change{Path: p, From: f, Op: ChangeMoved, Old: x[j], New: v})
+		}
+		diffNodes(x[j], v, f, p, options, changes)
+	}
+	for j, v := range x {
+		if !matched[j] {
+			p := from.Append(strconv.Itoa(j))
+			*changes = append(*changes, Change{Path: p, From: p, Op: ChangeRemoved, Old: v})
+		}
+	}
+}
@@ -2,25 +2,155 @@ package yamlwalker
 
 import (
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-func (walker *YamlWalker) encode() (node *yaml.Node, err error) {
-	switch walker.data.(type) {
-	case map[string]*YamlWalker:
-		node, err = walker.encodeMap()
+// encodeState keeps the anchored nodes already written while encoding a single tree
+type encodeState struct {
+	nodes   map[*YamlWalker]*yaml.Node // encoded anchored nodes
+	anchors map[string]*YamlWalker     // the node last written with the anchor
+	strict  bool                       // aliases must refer to the anchored nodes written before them
+}
+
+func newEncodeState(strict bool) *encodeState {
+	return &encodeState{
+		nodes:   make(map[*YamlWalker]*yaml.Node),
+		anchors: make(map[string]*YamlWalker),
+		strict:  strict,
+	}
+}
+
+// encode encodes the tree to be decoded with yaml.Node.Decode(),
+// an alias of a node outside the tree carries the copy of the node.
+func (walker *YamlWalker) encode() (*yaml.Node, error) {
+	return walker.encodeNode(newEncodeState(false))
+}
+
+// encodeDocument encodes the tree to be written as YAML document,
+// every alias must follow its anchored node written in the tree.
+func (walker *YamlWalker) encodeDocument() (*yaml.Node, error) {
+	return walker.encodeNode(newEncodeState(true))
+}
+
+func (walker *YamlWalker) encodeNode(state *encodeState) (node *yaml.Node, err error) {
+	if walker.alias != nil {
+		node, err = walker.encodeAlias(state)
+	} else {
+		switch walker.data.(type) {
+		case map[string]*YamlWalker:
+			node, err = walker.encodeMap(state)
+		case []*YamlWalker:
+			node, err = walker.encodeSeq(state)
+		default:
+			node = walker.encodeScalar()
+			state.addAnchor(walker, node)
+		}
+	}
+	if err != nil {
 		return
-	case []*YamlWalker:
-		node, err = walker.encodeSeq()
+	}
+
+	node.HeadComment = walker.comment.Head
+	node.LineComment = walker.comment.Line
+	node.FootComment = walker.comment.Foot
+
+	return
+}
+
+// addAnchor sets the anchor of the encoded node and records it as written.
+// Maps and sequences are recorded before their children, so that aliases inside refer to them.
+func (state *encodeState) addAnchor(walker *YamlWalker, node *yaml.Node) {
+	node.Anchor = walker.anchor
+	if len(walker.anchor) > 0 {
+		state.nodes[walker] = node
+		state.anchors[walker.anchor] = walker
+	}
+}
+
+func (walker *YamlWalker) encodeAlias(state *encodeState) (node *yaml.Node, err error) {
+	target := walker.resolve()
+	if len(target.anchor) == 0 {
+		err = ErrInvalidAlias
 		return
-	default:
-		node = walker.encodeScalar()
+	}
+
+	anchored, found := state.nodes[target]
+	if state.strict && (!found || state.anchors[target.anchor] != target) {
+		err = fmt.Errorf("%w: anchor '%s' is not written before the alias", ErrInvalidAlias, target.anchor)
 		return
 	}
+	if !found {
+		// the anchored node is encoded once so that the tree can be decoded with yaml.Node.Decode()
+		anchored, err = target.encodeNode(state)
+		if err != nil {
+			return
+		}
+	}
+
+	node = &yaml.Node{
+		Kind:  yaml.AliasNode,
+		Value: target.anchor,
+		Alias: anchored,
+	}
+
+	return
+}
+
+func (key yamlKey) encode(state *encodeState) (*yaml.Node, error) {
+	node := &yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Value: key.name,
+		Style: key.style,
+	}
+	if key.node != nil {
+		n, err := key.node.encodeNode(state)
+		if err != nil {
+			return nil, err
+		}
+		if n.Kind == yaml.ScalarNode {
+			n.Style = key.style
+		}
+		node = n
+	}
+
+	node.HeadComment = key.comment.Head
+	node.LineComment = key.comment.Line
+	node.FootComment = key.comment.Foot
+	return node, nil
 }
 
-func (walker *YamlWalker) encodeMap() (node *yaml.Node, err error) {
+// flowText returns the node written in flow style on a single line
+func flowText(walker *YamlWalker) (string, error) {
+	node, err := walker.encode()
+	if err != nil {
+		return "", err
+	}
+	setFlowStyle(node)
+
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func setFlowStyle(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+		node.Style = yaml.FlowStyle
+	}
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	for _, n := range node.Content {
+		setFlowStyle(n)
+	}
+}
+
+func (walker *YamlWalker) encodeMap(state *encodeState) (node *yaml.Node, err error) {
 	x := walker.data.(map[string]*YamlWalker)
 
 	numKeys := len(walker.keys)
@@ -30,45 +160,61 @@ func (walker *YamlWalker) encodeMap() (node *yaml.Node, err error) {
 		return
 	}
 
-	count := len(x) * 2
-
 	n := &yaml.Node{
 		Kind:    yaml.MappingNode,
-		Content: make([]*yaml.Node, count),
+		Content: make([]*yaml.Node, 0, len(x)*2),
 		Style:   walker.style,
 	}
+	state.addAnchor(walker, n)
 
-	for i := 0; i < count; i += 2 {
-		keyIdx := i / 2
-		key := walker.keys[keyIdx]
-
-		keyNode := &yaml.Node{
-			Kind:  yaml.ScalarNode,
-			Value: key.name,
-			Style: key.style,
-		}
-		n.Content[i] = keyNode
-
+	// entries are encoded in document order, so that anchors are written before their aliases
+	entries := make([]duplicate, 0, len(walker.keys))
+	duplicates := []duplicate{}
+	for _, key := range walker.keys {
+		duplicates = append(duplicates, key.duplicates...)
 		value, found := x[key.name]
 		if !found {
 			err = ErrKeyMismatch
-			fmt.Printf("%v\n", err)
 			return
 		}
-		content, e := value.encode()
+		entries = append(entries, duplicate{key: key, node: value})
+	}
+
+	// repeated keys are placed back to their index among the keys
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return duplicates[i].index < duplicates[j].index
+	})
+	for _, d := range duplicates {
+		i := d.index
+		if i > len(entries) {
+			i = len(entries)
+		}
+		entries = append(entries[:i], append([]duplicate{d}, entries[i:]...)...)
+	}
+
+	for _, entry := range entries {
+		keyNode, e := entry.key.encode(state)
 		if e != nil {
 			err = e
 			return
 		}
-		n.Content[i+1] = content
+		content, e := entry.node.encodeNode(state)
+		if e != nil {
+			err = e
+			return
+		}
+		n.Content = append(n.Content, keyNode, content)
 	}
 
+	if n.Style&yaml.FlowStyle != 0 {
+		setFlowNulls(n)
+	}
 	node = n
 
 	return
 }
 
-func (walker *YamlWalker) encodeSeq() (node *yaml.Node, err error) {
+func (walker *YamlWalker) encodeSeq(state *encodeState) (node *yaml.Node, err error) {
 	x := walker.data.([]*YamlWalker)
 
 	node = &yaml.Node{
@@ -76,26 +222,92 @@ func (walker *YamlWalker) encodeSeq() (node *yaml.Node, err error) {
 		Content: make([]*yaml.Node, len(x)),
 		Style:   walker.style,
 	}
+	state.addAnchor(walker, node)
 
 	for i, value := range x {
-		n, e := value.encode()
+		n, e := value.encodeNode(state)
 		if e != nil {
 			err = e
 			return
 		}
 		node.Content[i] = n
 	}
+	if node.Style&yaml.FlowStyle != 0 {
+		setFlowNulls(node)
+	}
 
 	return
 }
 
+// setFlowNulls writes empty nulls inside the flow collection as null,
+// the empty value can not be written in flow style.
+func setFlowNulls(node *yaml.Node) {
+	for _, n := range node.Content {
+		if n.Kind == yaml.ScalarNode && len(n.Value) == 0 && n.ShortTag() == "!!null" {
+			n.Value = "null"
+		}
+		setFlowNulls(n)
+	}
+}
+
 func (walker *YamlWalker) encodeScalar() (node *yaml.Node) {
 	node = &yaml.Node{
 		Kind:  yaml.ScalarNode,
 		Style: walker.style,
+		Tag:   walker.tag,
+	}
+
+	if walker.raw != nil {
+		node.Value = *walker.raw
+		return
+	}
+
+	if walker.data == nil {
+		node.Value, node.Tag = "null", "!!null"
+		return
 	}
 
-	node.Value = fmt.Sprintf("%v", walker.data)
+	var tag string
+	node.Value, tag = encodeValue(walker.data)
+	if len(node.Tag) == 0 {
+		node.Tag = tag
+	}
 
 	return
 }
+
+// encodeValue formats the value and returns it along with the tag of its type
+func encodeValue(data interface{}) (value string, tag string) {
+	switch x := data.(type) {
+	case string:
+		return x, "!!str"
+	case bool:
+		return strconv.FormatBool(x), "!!bool"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", x), "!!int"
+	case float32:
+		return encodeFloat(float64(x)), "!!float"
+	case float64:
+		return encodeFloat(x), "!!float"
+	case time.Time:
+		return x.Format(time.RFC3339Nano), "!!timestamp"
+	}
+	return fmt.Sprintf("%v", data), ""
+}
+
+func encodeFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	}
+
+	value := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(value, ".e") {
+		value += ".0"
+	}
+	return value
+}
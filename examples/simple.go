@@ -31,26 +31,13 @@ func main() {
 		panic(err)
 	}
 
-	next := true
-	for next {
-		next = false
-		sections, ok := yw.Value().(map[string]*yamlwalker.YamlWalker)
-		if !ok {
-			continue
-		}
-
-		srvM, found := sections["servers"]
-		if !found {
-			continue
-		}
-
-		srv, ok := srvM.Value().([]*yamlwalker.YamlWalker)
-		if !ok {
-			continue
-		}
-		for _, v := range srv {
-			m := v.Value().(map[string]*yamlwalker.YamlWalker)
-			url := m["url"].Value().(string)
+	servers, err := yw.AsSlice("servers")
+	if err == nil {
+		for i := range servers {
+			url, err := yw.AsString(fmt.Sprintf("servers.%d.url", i))
+			if err != nil {
+				continue
+			}
 			fmt.Printf("S:%+v\n", url)
 		}
 	}
@@ -0,0 +1,300 @@
+package yamlwalker
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// SequenceStrategy defines how Merge() combines two sequences
+type SequenceStrategy int
+
+const (
+	// SequenceReplace replaces items of the existing sequence by items of the other one
+	SequenceReplace SequenceStrategy = iota
+	// SequenceAppend appends items of the other sequence to the existing one
+	SequenceAppend
+	// SequenceDedupe appends items of the other sequence which are not present in the existing one
+	SequenceDedupe
+	// SequenceMergeByKey merges maps having the same value of the key field,
+	// items without matching map are appended
+	SequenceMergeByKey
+)
+
+// MergeOption configures Merge()
+type MergeOption func(*mergeOptions)
+
+type mergeOptions struct {
+	sequence     sequenceRule
+	paths        []sequenceRule
+	keepExisting bool
+	nullDeletes  bool
+	err          error // the first invalid pattern
+}
+
+type sequenceRule struct {
+	pattern  Path
+	strategy SequenceStrategy
+	key      string
+}
+
+// WithSequenceStrategy sets the strategy used for all sequences.
+// The key is the name of the field to match items by for SequenceMergeByKey.
+// Default strategy is SequenceReplace.
+func WithSequenceStrategy(strategy SequenceStrategy, key ...string) MergeOption {
+	return func(o *mergeOptions) {
+		o.sequence = newSequenceRule(nil, strategy, key)
+	}
+}
+
+// WithPathStrategy sets the strategy used for the sequence at the path.
+// The path is a pattern, element "*" matches any key or index, e.g. "spec.template.spec.containers"
+// or "services.*.ports". The key is the name of the field to match items by for SequenceMergeByKey.
+// Invalid pattern makes Merge() fail with ErrInvalidPath.
+func WithPathStrategy(path string, strategy SequenceStrategy, key ...string) MergeOption {
+	return func(o *mergeOptions) {
+		pattern, err := parsePattern(path)
+		if err != nil {
+			if o.err == nil {
+				o.err = err
+			}
+			return
+		}
+		o.paths = append(o.paths, newSequenceRule(pattern, strategy, key))
+	}
+}
+
+// WithKeepExisting keeps existing scalars instead of overriding them by scalars of the other tree
+func WithKeepExisting() MergeOption {
+	return func(o *mergeOptions) {
+		o.keepExisting = true
+	}
+}
+
+// WithNullDeletes deletes keys which are null in the other tree
+func WithNullDeletes() MergeOption {
+	return func(o *mergeOptions) {
+		o.nullDeletes = true
+	}
+}
+
+func newSequenceRule(pattern Path, strategy SequenceStrategy, key []string) sequenceRule {
+	rule := sequenceRule{pattern: pattern, strategy: strategy}
+	if len(key) > 0 {
+		rule.key = key[0]
+	}
+	return rule
+}
+
+// Merge deep merges the other tree into the node.
+//
+// Maps are merged key by key: existing keys keep their position and style,
+// new keys are appended at the end in the order and style of the other tree.
+// Scalars and nodes of different kinds are overridden by the other tree.
+// Sequences are combined according to the strategy, see WithSequenceStrategy() and WithPathStrategy().
+//
+// Nodes taken from the other tree are copied, the trees do not share nodes after the merge.
+// If the pattern of an option is invalid err wraps ErrInvalidPath and the node is left untouched.
+func (walker *YamlWalker) Merge(other *YamlWalker, opts ...MergeOption) error {
+	options := &mergeOptions{}
+	for _, o := range opts {
+		o(options)
+	}
+	if options.err != nil {
+		return options.err
+	}
+
+	return walker.merge(other.resolve(), Path{}, options)
+}
+
+func (walker *YamlWalker) merge(other *YamlWalker, path Path, options *mergeOptions) error {
+	switch x := other.data.(type) {
+	case map[string]*YamlWalker:
+		if _, ok := walker.data.(map[string]*YamlWalker); ok {
+			return walker.mergeMap(other, path, options)
+		}
+	case []*YamlWalker:
+		if _, ok := walker.data.([]*YamlWalker); ok {
+			return walker.mergeSeq(x, path, options)
+		}
+	default:
+		if options.keepExisting && walker.isScalar() {
+			return nil
+		}
+	}
+
+	walker.replaceWith(other.deepCopy())
+	return nil
+}
+
+func (walker *YamlWalker) mergeMap(other *YamlWalker, path Path, options *mergeOptions) error {
+	return walker.mergeEntries(other, other.keys, entryMerge{
+		remove: func(value *YamlWalker) (bool, error) {
+			return options.nullDeletes && value.data == nil, nil
+		},
+		add: func(value *YamlWalker) *YamlWalker {
+			return value.deepCopy()
+		},
+		merge: func(name string, existing, value *YamlWalker) error {
+			return existing.merge(value, path.Append(name), options)
+		},
+	})
+}
+
+// entryMerge defines how mergeEntries() combines the entries of two maps
+type entryMerge struct {
+	remove func(value *YamlWalker) (bool, error)                // reports whether the entry is deleted
+	add    func(value *YamlWalker) *YamlWalker                  // returns the new entry to append
+	merge  func(name string, existing, value *YamlWalker) error // merges the value into the existing entry
+}
+
+// mergeEntries merges the entries of the map other listed in keys into the map of the node:
+// removed entries are deleted, new ones are appended and existing ones are merged.
+// An existing alias is replaced by the copy of the anchored node first,
+// merging into the alias must not change the anchored node.
+func (walker *YamlWalker) mergeEntries(other *YamlWalker, keys []yamlKey, how entryMerge) error {
+	m := walker.data.(map[string]*YamlWalker)
+	x := other.data.(map[string]*YamlWalker)
+	for _, k := range keys {
+		value, found := x[k.name]
+		if !found {
+			return ErrKeyMismatch
+		}
+		value = value.resolve()
+
+		remove, err := how.remove(value)
+		if err != nil {
+			return err
+		}
+		existing, exists := m[k.name]
+		if remove {
+			if exists {
+				if err := walker.deleteNode([]string{k.name}); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if !exists {
+			if err := walker.appendEntry(k, how.add(value)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if existing.alias != nil {
+			existing = existing.expandAlias()
+			m[k.name] = existing
+		}
+		if err := how.merge(k.name, existing, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (walker *YamlWalker) mergeSeq(x []*YamlWalker, path Path, options *mergeOptions) error {
+	s := walker.data.([]*YamlWalker)
+	rule := options.sequenceRule(path)
+
+	switch rule.strategy {
+	case SequenceAppend:
+		for _, v := range x {
+			s = append(s, v.deepCopy())
+		}
+	case SequenceDedupe:
+		for _, v := range x {
+			if !containsEqual(s, v) {
+				s = append(s, v.deepCopy())
+			}
+		}
+	case SequenceMergeByKey:
+		for _, v := range x {
+			index := findByKey(s, v, rule.key)
+			if index < 0 {
+				s = append(s, v.deepCopy())
+				continue
+			}
+			existing := s[index]
+			if existing.alias != nil {
+				existing = existing.expandAlias()
+				s[index] = existing
+			}
+			if err := existing.merge(v.resolve(), path.Append(strconv.Itoa(index)), options); err != nil {
+				return err
+			}
+		}
+	default:
+		s = make([]*YamlWalker, len(x))
+		for i, v := range x {
+			s[i] = v.deepCopy()
+		}
+	}
+
+	walker.data = s
+	return nil
+}
+
+func (options *mergeOptions) sequenceRule(path Path) sequenceRule {
+	if rule, found := matchRule(options.paths, path); found {
+		return rule
+	}
+	return options.sequence
+}
+
+// parsePattern parses the path pattern of an option, the error names the pattern
+func parsePattern(path string) (Path, error) {
+	pattern, err := ParsePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("pattern '%s': %w", path, err)
+	}
+	return pattern, nil
+}
+
+// matchRule returns the first rule having the pattern matching the path
+func matchRule(rules []sequenceRule, path Path) (sequenceRule, bool) {
+	for _, rule := range rules {
+		if matchPath(rule.pattern, path) {
+			return rule, true
+		}
+	}
+	return sequenceRule{}, false
+}
+
+func containsEqual(s []*YamlWalker, node *YamlWalker) bool {
+	for _, v := range s {
+		if v.equal(node, sameValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// findByKey returns index of the map in s having the same value of the key field as node
+// or -1 if there is no such map.
+func findByKey(s []*YamlWalker, node *YamlWalker, key string) int {
+	value, err := node.child(key)
+	if err != nil {
+		return -1
+	}
+	return findByKeyValue(s, value, key)
+}
+
+// findByKeyValue returns index of the map in s having the scalar value in the key field
+// or -1 if there is no such map.
+func findByKeyValue(s []*YamlWalker, value *YamlWalker, key string) int {
+	if !value.isScalar() {
+		return -1
+	}
+	for i, v := range s {
+		if _, ok := v.resolve().data.(map[string]*YamlWalker); !ok {
+			continue
+		}
+		existing, err := v.child(key)
+		if err == nil && existing.isScalar() && sameValue(existing.data, value.data) {
+			return i
+		}
+	}
+	return -1
+}
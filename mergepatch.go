@@ -0,0 +1,109 @@
+package yamlwalker
+
+// ApplyMergePatch applies JSON Merge Patch (RFC 7396) to the tree.
+//
+// Keys of the patch with null value are deleted, other keys are merged recursively.
+// Overridden keys stay in their position and keep their style and comments,
+// new keys are appended at the end in the order and style of the patch.
+// Sequences and scalars of the patch replace the existing nodes,
+// scalars keep their style unless the type of the value changes.
+// Nodes reached through an alias are merged into the copy placed instead of the alias,
+// the anchored node stays unchanged.
+//
+// Nodes taken from the patch are copied, the trees do not share nodes after the merge.
+func (walker *YamlWalker) ApplyMergePatch(patch *YamlWalker) error {
+	return walker.mergePatch(patch.resolve())
+}
+
+func (walker *YamlWalker) mergePatch(patch *YamlWalker) error {
+	if _, ok := patch.data.(map[string]*YamlWalker); !ok {
+		if patch.isScalar() {
+			// scalars keep the style unless the type changes
+			walker.assign(patch.deepCopy())
+		} else {
+			walker.replaceWith(patch.deepCopy())
+		}
+		return nil
+	}
+
+	if _, ok := walker.data.(map[string]*YamlWalker); !ok {
+		empty := NewYamlWalker(patch.style)
+		empty.Update(make(map[string]*YamlWalker))
+		walker.replaceWith(empty)
+	}
+	return walker.mergeEntries(patch, patch.keys, entryMerge{
+		remove: func(value *YamlWalker) (bool, error) {
+			return value.isNull(), nil
+		},
+		add: func(value *YamlWalker) *YamlWalker {
+			node := value.deepCopy()
+			node.removeNulls()
+			return node
+		},
+		merge: func(name string, existing, value *YamlWalker) error {
+			return existing.mergePatch(value)
+		},
+	})
+}
+
+// removeNulls deletes keys with null values from the map and all nested maps
+func (walker *YamlWalker) removeNulls() {
+	m, ok := walker.data.(map[string]*YamlWalker)
+	if !ok {
+		return
+	}
+
+	keys := make([]yamlKey, 0, len(walker.keys))
+	for _, k := range walker.keys {
+		if m[k.name].resolve().isNull() {
+			delete(m, k.name)
+			continue
+		}
+		m[k.name].removeNulls()
+		keys = append(keys, k)
+	}
+	walker.keys = keys
+}
+
+// CreateMergePatch returns JSON Merge Patch (RFC 7396) turning the tree from into the tree to.
+//
+// Keys missing in the tree to are set to null, added and changed keys are copied from the tree to
+// in its order and style. Sequences are replaced as a whole.
+// Keys of the tree to holding null can not be expressed by merge patch, they are deleted when applied.
+func CreateMergePatch(from, to *YamlWalker) *YamlWalker {
+	a, b := from.resolve(), to.resolve()
+	x, okA := a.data.(map[string]*YamlWalker)
+	y, okB := b.data.(map[string]*YamlWalker)
+	if !okA || !okB {
+		return b.deepCopy()
+	}
+
+	patch := NewYamlWalker(b.style)
+	patch.Update(make(map[string]*YamlWalker))
+	for _, k := range b.keys {
+		value := y[k.name]
+		old, found := x[k.name]
+		var node *YamlWalker
+		switch {
+		case !found:
+			node = value.deepCopy()
+		case old.resolve().isMap() && value.resolve().isMap():
+			node = CreateMergePatch(old, value)
+			if len(node.keys) == 0 {
+				continue
+			}
+		case !old.equal(value, sameValue):
+			node = value.deepCopy()
+		default:
+			continue
+		}
+		_ = patch.appendEntry(yamlKey{name: k.name, style: k.style, node: k.node}, node)
+	}
+	for _, k := range a.keys {
+		if _, found := y[k.name]; !found {
+			_ = patch.appendEntry(yamlKey{name: k.name, style: k.style, node: k.node}, NewNull())
+		}
+	}
+
+	return patch
+}
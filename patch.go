@@ -0,0 +1,394 @@
+package yamlwalker
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch")
+	ErrTestFailed   = errors.New("patch test failed")
+)
+
+type patchOperation struct {
+	Op    string    `yaml:"op"`
+	Path  *string   `yaml:"path"`
+	From  *string   `yaml:"from"`
+	Value yaml.Node `yaml:"value"`
+}
+
+type patchOp struct {
+	op    string
+	path  Path
+	from  Path
+	value *YamlWalker
+}
+
+// ApplyPatch applies JSON Patch (RFC 6902) to the tree.
+// The patch is a sequence of operations written in JSON or YAML, e.g.
+//
+//	[{"op": "replace", "path": "/spec/replicas", "value": 3}]
+//
+// Supported operations are add, remove, replace, move, copy and test,
+// paths are JSON Pointers (RFC 6901), "-" refers to the end of a sequence.
+// Added values get the default style, added keys are appended at the end of the map.
+// Untouched nodes keep their comments, styles and order of keys,
+// replaced values keep the key together with its comments.
+// Nodes reached through an alias are modified on the copy placed instead of the alias,
+// the anchored node stays unchanged.
+//
+// The patch is applied atomically: if any operation fails the tree is left untouched.
+// If the patch can not be parsed err is ErrInvalidPatch,
+// if the test operation fails err is ErrTestFailed.
+// The error of the failed operation wraps ErrNotFound, ErrInvalidType or ErrInvalidRange.
+func (walker *YamlWalker) ApplyPatch(patch []byte) error {
+	ops, err := parsePatch(patch)
+	if err != nil {
+		return err
+	}
+
+	if err := walker.deepCopy().applyPatch(ops); err != nil {
+		return err
+	}
+	return walker.applyPatch(ops)
+}
+
+func parsePatch(patch []byte) ([]*patchOp, error) {
+	var operations []patchOperation
+	if err := yaml.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+	}
+
+	ops := make([]*patchOp, len(operations))
+	for i, o := range operations {
+		op, err := o.parse()
+		if err != nil {
+			return nil, fmt.Errorf("%w: operation %d: %s", ErrInvalidPatch, i, err)
+		}
+		ops[i] = op
+	}
+	return ops, nil
+}
+
+func (o *patchOperation) parse() (*patchOp, error) {
+	op := &patchOp{op: o.Op}
+	switch o.Op {
+	case "add", "remove", "replace", "move", "copy", "test":
+	default:
+		return nil, fmt.Errorf("unknown operation '%s'", o.Op)
+	}
+
+	if o.Path == nil {
+		return nil, errors.New("missing path")
+	}
+	var err error
+	if op.path, err = parsePointer(*o.Path); err != nil {
+		return nil, err
+	}
+
+	switch o.Op {
+	case "move", "copy":
+		if o.From == nil {
+			return nil, errors.New("missing from")
+		}
+		if op.from, err = parsePointer(*o.From); err != nil {
+			return nil, err
+		}
+		if o.Op == "move" && len(op.from) < len(op.path) && equalPaths(op.from, op.path[:len(op.from)]) {
+			return nil, errors.New("can not move the node into its child")
+		}
+	case "add", "replace", "test":
+		if o.Value.Kind == 0 {
+			return nil, errors.New("missing value")
+		}
+		if op.value, err = NewYamlWalker().decode(&o.Value, newDecodeState()); err != nil {
+			return nil, err
+		}
+		op.value.clearStyle()
+	}
+
+	return op, nil
+}
+
+// parsePointer converts JSON Pointer to the path
+func parsePointer(pointer string) (Path, error) {
+	if len(pointer) == 0 {
+		return Path{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("pointer '%s' must start with '/'", pointer)
+	}
+
+	path := Path{}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(token, "~1", "/")
+		token = strings.ReplaceAll(token, "~0", "~")
+		path = append(path, token)
+	}
+	return path, nil
+}
+
+// clearStyle resets the style of the node, its children and keys to the default one
+func (walker *YamlWalker) clearStyle() {
+	walker.style = 0
+	for i := range walker.keys {
+		walker.keys[i].style = 0
+	}
+
+	switch x := walker.data.(type) {
+	case map[string]*YamlWalker:
+		for _, v := range x {
+			v.clearStyle()
+		}
+	case []*YamlWalker:
+		for _, v := range x {
+			v.clearStyle()
+		}
+	}
+}
+
+func (walker *YamlWalker) applyPatch(ops []*patchOp) error {
+	for i, op := range ops {
+		if err := walker.applyOp(op); err != nil {
+			return fmt.Errorf("patch operation %d (%s '%s'): %w", i, op.op, op.path, err)
+		}
+	}
+	return nil
+}
+
+func (walker *YamlWalker) applyOp(op *patchOp) error {
+	switch op.op {
+	case "add":
+		return walker.patchAdd(op.path, op.value.deepCopy())
+	case "remove":
+		_, err := walker.patchRemove(op.path)
+		return err
+	case "replace":
+		return walker.patchReplace(op.path, op.value.deepCopy())
+	case "move":
+		if equalPaths(op.from, op.path) {
+			_, err := walker.pointerGet(op.from)
+			return err
+		}
+		node, err := walker.patchRemove(op.from)
+		if err != nil {
+			return err
+		}
+		return walker.patchAdd(op.path, node)
+	case "copy":
+		node, err := walker.pointerGet(op.from)
+		if err != nil {
+			return err
+		}
+		return walker.patchAdd(op.path, node.deepCopy())
+	case "test":
+		node, err := walker.pointerGet(op.path)
+		if err != nil {
+			return err
+		}
+		if !node.equal(op.value, sameByValue) {
+			return ErrTestFailed
+		}
+	}
+	return nil
+}
+
+func (walker *YamlWalker) patchAdd(path Path, node *YamlWalker) error {
+	if len(path) == 0 {
+		walker.replaceWith(node)
+		return nil
+	}
+
+	parent, err := walker.patchParent(path)
+	if err != nil {
+		return err
+	}
+	name := path[len(path)-1]
+
+	switch x := parent.data.(type) {
+	case map[string]*YamlWalker:
+		if old, found := x[name]; found {
+			old.replaceNode(node)
+			return nil
+		}
+		return parent.appendNode([]string{name}, node, 0)
+	case []*YamlWalker:
+		index, err := pointerIndex(name, len(x), true)
+		if err != nil {
+			return err
+		}
+		return parent.insert([]string{}, index, node)
+	}
+	return ErrInvalidType
+}
+
+func (walker *YamlWalker) patchRemove(path Path) (*YamlWalker, error) {
+	if len(path) == 0 {
+		removed := walker.deepCopy()
+		walker.Update(nil)
+		return removed, nil
+	}
+
+	parent, err := walker.patchParent(path)
+	if err != nil {
+		return nil, err
+	}
+	name := path[len(path)-1]
+
+	switch x := parent.data.(type) {
+	case map[string]*YamlWalker:
+		node, found := x[name]
+		if !found {
+			return nil, ErrNotFound
+		}
+		return node, parent.deleteNode([]string{name})
+	case []*YamlWalker:
+		index, err := pointerIndex(name, len(x), false)
+		if err != nil {
+			return nil, err
+		}
+		node := x[index]
+		return node, parent.remove([]string{}, index)
+	}
+	return nil, ErrInvalidType
+}
+
+func (walker *YamlWalker) patchReplace(path Path, node *YamlWalker) error {
+	if len(path) == 0 {
+		walker.replaceWith(node)
+		return nil
+	}
+
+	parent, err := walker.patchParent(path)
+	if err != nil {
+		return err
+	}
+	name := path[len(path)-1]
+
+	switch x := parent.data.(type) {
+	case map[string]*YamlWalker:
+		old, found := x[name]
+		if !found {
+			return ErrNotFound
+		}
+		old.replaceNode(node)
+		return nil
+	case []*YamlWalker:
+		index, err := pointerIndex(name, len(x), false)
+		if err != nil {
+			return err
+		}
+		x[index].replaceNode(node)
+		return nil
+	}
+	return ErrInvalidType
+}
+
+// sameByValue reports whether the scalars are equal for the test operation,
+// numbers are compared by value, i.e. 1 equals 1.0.
+func sameByValue(a, b interface{}) bool {
+	return compareValues(a, b) == 0
+}
+
+// replaceNode replaces the node in place, so that its anchor and the aliases of it stay valid.
+// The node keeps its comment unless the new node has its own one.
+func (walker *YamlWalker) replaceNode(node *YamlWalker) {
+	walker.replaceWith(node)
+	if node.comment != (Comment{}) {
+		walker.comment = node.comment
+	}
+}
+
+// pointerGet returns the node at the path
+func (walker *YamlWalker) pointerGet(path Path) (*YamlWalker, error) {
+	n := walker.resolve()
+	for _, part := range path {
+		child, err := n.pointerChild(part, false)
+		if err != nil {
+			return nil, err
+		}
+		n = child
+	}
+	return n, nil
+}
+
+// patchParent returns the parent of the node at the path to be modified.
+// Aliases on the way are replaced by copies of the anchored nodes.
+func (walker *YamlWalker) patchParent(path Path) (*YamlWalker, error) {
+	n := walker.resolve()
+	for _, part := range path[:len(path)-1] {
+		child, err := n.pointerChild(part, true)
+		if err != nil {
+			return nil, err
+		}
+		n = child
+	}
+	return n, nil
+}
+
+// pointerChild returns the resolved child specified by the JSON Pointer token.
+// If expand is set the alias is replaced by the copy of the anchored node first.
+func (walker *YamlWalker) pointerChild(part string, expand bool) (*YamlWalker, error) {
+	var child *YamlWalker
+	switch x := walker.data.(type) {
+	case map[string]*YamlWalker:
+		c, found := x[part]
+		if !found {
+			return nil, ErrNotFound
+		}
+		if expand && c.alias != nil {
+			c = c.expandAlias()
+			x[part] = c
+		}
+		child = c
+	case []*YamlWalker:
+		index, err := pointerIndex(part, len(x), false)
+		if err != nil {
+			return nil, err
+		}
+		child = x[index]
+		if expand && child.alias != nil {
+			child = child.expandAlias()
+			x[index] = child
+		}
+	default:
+		return nil, ErrInvalidType
+	}
+	return child.resolve(), nil
+}
+
+// pointerIndex converts JSON Pointer token to an index of a sequence of length size.
+// If end is set "-" refers to the index following the last item.
+func pointerIndex(part string, size int, end bool) (int, error) {
+	if end && part == "-" {
+		return size, nil
+	}
+	if len(part) == 0 || (len(part) > 1 && part[0] == '0') || strings.TrimLeft(part, "0123456789") != "" {
+		return 0, ErrInvalidType
+	}
+
+	index, err := strconv.Atoi(part)
+	if err != nil {
+		return 0, ErrInvalidRange
+	}
+	if index > size || (!end && index == size) {
+		return 0, ErrInvalidRange
+	}
+	return index, nil
+}
+
+func equalPaths(a, b Path) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+package yamlwalker
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPath = errors.New("invalid path")
+)
+
+// Path is a list of path elements: key names of maps and indexes of sequences.
+// Elements are not escaped, so Path{"labels", "app.kubernetes.io/name"} addresses
+// the key "app.kubernetes.io/name" of the map "labels".
+// Use String() to get the path in the form accepted by methods of YamlWalker.
+type Path []string
+
+// ParsePath parses the string representation of the path.
+//
+// Elements are separated by Separator.
+// An element may be quoted with double quotes ("a.b") or single quotes ('a.b')
+// to include separators, brackets and quotes into a key name.
+// Inside double quotes backslash escapes the next character, inside single quotes
+// the quote is escaped by doubling it as in YAML.
+// Outside quotes backslash escapes the next character, i.e. a\.b is the key "a.b".
+// Sequence indexes are separate elements or bracketed: "servers.0" and "servers[0]" are the same.
+// Empty path is the path to the top node, empty key name is written as "".
+//
+// It returns ErrInvalidPath if quotes or brackets are not balanced or the bracket does not contain an integer.
+func ParsePath(path string) (Path, error) {
+	parts := Path{}
+	if len(path) == 0 {
+		return parts, nil
+	}
+
+	sep := Separator[0]
+	i := 0
+	for {
+		name, quoted, end, err := parseElement(path, i)
+		if err != nil {
+			return nil, err
+		}
+		i = end
+
+		indexes := []string{}
+		for i < len(path) && path[i] == '[' {
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, ErrInvalidPath
+			}
+			index := path[i+1 : i+end]
+			if _, e := strconv.Atoi(index); e != nil {
+				return nil, ErrInvalidPath
+			}
+			indexes = append(indexes, index)
+			i += end + 1
+		}
+
+		if quoted || len(name) > 0 || len(indexes) == 0 {
+			parts = append(parts, name)
+		}
+		parts = append(parts, indexes...)
+
+		if i == len(path) {
+			break
+		}
+		if path[i] != sep {
+			return nil, ErrInvalidPath
+		}
+		i++
+	}
+
+	return parts, nil
+}
+
+// parseElement reads the key name started at index start, the name is either quoted or
+// ends before the separator or the opening bracket.
+// It returns the name and the index following it.
+func parseElement(path string, start int) (name string, quoted bool, end int, err error) {
+	var b strings.Builder
+	sep := Separator[0]
+	i := start
+
+	if i < len(path) && (path[i] == '"' || path[i] == '\'') {
+		end, err = parseQuoted(path, i, &b)
+		if err != nil {
+			return
+		}
+		name = b.String()
+		quoted = true
+		return
+	}
+
+	for i < len(path) && path[i] != sep && path[i] != '[' {
+		if path[i] == '\\' {
+			i++
+			if i == len(path) {
+				err = ErrInvalidPath
+				return
+			}
+		}
+		b.WriteByte(path[i])
+		i++
+	}
+	name = b.String()
+	end = i
+	return
+}
+
+// parseQuoted reads the quoted element started at index start into name
+// and returns the index following the closing quote.
+func parseQuoted(path string, start int, name *strings.Builder) (end int, err error) {
+	quote := path[start]
+	for i := start + 1; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+			if i == len(path) {
+				return 0, ErrInvalidPath
+			}
+			name.WriteByte(path[i])
+		case c == quote && quote == '\'' && i+1 < len(path) && path[i+1] == '\'':
+			i++
+			name.WriteByte(c)
+		case c == quote:
+			return i + 1, nil
+		default:
+			name.WriteByte(c)
+		}
+	}
+	return 0, ErrInvalidPath
+}
+
+// String returns the path in the form accepted by ParsePath.
+// Elements containing separators, brackets, quotes or backslashes are quoted.
+func (path Path) String() string {
+	elements := make([]string, len(path))
+	for i, p := range path {
+		elements[i] = quotePathElement(p)
+	}
+	return strings.Join(elements, Separator)
+}
+
+// Append returns a new path with the elements added at the end
+func (path Path) Append(elements ...string) Path {
+	p := make(Path, 0, len(path)+len(elements))
+	p = append(p, path...)
+	return append(p, elements...)
+}
+
+// matchPath reports whether the path matches the pattern,
+// element "*" of the pattern matches any element of the path.
+func matchPath(pattern Path, path Path) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Index returns the path element addressing the item of sequence at the index
+func Index(index int) string {
+	return strconv.Itoa(index)
+}
+
+func quotePathElement(element string) string {
+	if len(element) > 0 && !strings.ContainsAny(element, Separator+"[]\"'\\") {
+		return element
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(element); i++ {
+		if element[i] == '"' || element[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(element[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
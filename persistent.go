@@ -0,0 +1,289 @@
+package yamlwalker
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// PersistentWalker is an immutable version of a tree.
+//
+// Edit methods do not change the version, they return a new one sharing all untouched nodes
+// with the old version. Only the nodes on the path to the changed node are copied, together with
+// their maps and sequences, so that an edit costs O(depth) times the size of the maps and sequences
+// on the path and keeping many versions is cheap.
+// Nodes reached through an alias are changed on the copy placed instead of the alias,
+// aliases of the changed anchored node refer to its copy in the new version.
+// Finding such aliases takes a walk over the whole tree, so an edit of a node having an anchor
+// or lying under one costs O(size of the tree), the nodes are still shared.
+//
+// Versions are safe for concurrent reads. Nodes returned by Get() are shared by versions
+// and must not be modified, use Walker() to get a tree to modify.
+type PersistentWalker struct {
+	root *YamlWalker
+}
+
+// NewPersistentWalker creates the first version holding the copy of the tree
+func NewPersistentWalker(root *YamlWalker) *PersistentWalker {
+	return &PersistentWalker{root: root.Clone()}
+}
+
+// Walker returns the copy of the tree of the version to be modified
+func (p *PersistentWalker) Walker() *YamlWalker {
+	return p.root.Clone()
+}
+
+// MarshalYAML encodes the version to YAML
+func (p *PersistentWalker) MarshalYAML() (interface{}, error) {
+	return p.root.MarshalYAML()
+}
+
+// Get returns the node specified by path, see YamlWalker.Get().
+// The node is shared by versions and must not be modified.
+func (p *PersistentWalker) Get(path string) (*YamlWalker, error) {
+	return p.root.Get(path)
+}
+
+// GetValue returns the value of the node specified by path or <nil> if node does not exists,
+// see YamlWalker.GetValue(). Children of maps and sequences must not be modified.
+func (p *PersistentWalker) GetValue(path string) interface{} {
+	return p.root.GetValue(path)
+}
+
+// Keys returns key names of the map specified by path in document order, see YamlWalker.Keys()
+func (p *PersistentWalker) Keys(path string) ([]string, error) {
+	return p.root.Keys(path)
+}
+
+// Decode decodes the node specified by path into out, see YamlWalker.Decode()
+func (p *PersistentWalker) Decode(path string, out interface{}) error {
+	return p.root.Decode(path, out)
+}
+
+// SetValue returns new version with the value of the node at the path updated, see YamlWalker.Update().
+// If path does not exists it returns ErrNotFound.
+func (p *PersistentWalker) SetValue(path string, value interface{}) (*PersistentWalker, error) {
+	parts, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.edit(parts, func(root *YamlWalker) error {
+		node, err := root.findNode(parts)
+		if err != nil {
+			return err
+		}
+		node.Update(value)
+		return nil
+	})
+}
+
+// Set returns new version with the node at the path set to the copy of node, see YamlWalker.Set()
+func (p *PersistentWalker) Set(path string, node *YamlWalker) (*PersistentWalker, error) {
+	return p.SetValue(path, node)
+}
+
+// Append returns new version with the copy of node appended to the map at the path,
+// see YamlWalker.Append()
+func (p *PersistentWalker) Append(path string, node *YamlWalker, keyStyle ...yaml.Style) (*PersistentWalker, error) {
+	if len(path) == 0 {
+		return nil, ErrKeyMismatch
+	}
+
+	style := yaml.Style(0)
+	if len(keyStyle) > 0 {
+		style = keyStyle[0]
+	}
+
+	parts, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	node = node.Clone()
+	return p.edit(parts[:len(parts)-1], func(root *YamlWalker) error {
+		return root.appendNode(parts, node, style)
+	})
+}
+
+// Delete returns new version without the node at the path, see YamlWalker.Delete()
+func (p *PersistentWalker) Delete(path string) (*PersistentWalker, error) {
+	if len(path) == 0 {
+		return nil, ErrKeyMismatch
+	}
+
+	parts, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.edit(parts[:len(parts)-1], func(root *YamlWalker) error {
+		return root.deleteNode(parts)
+	})
+}
+
+// Insert returns new version with the copy of node inserted into the sequence at the path,
+// see YamlWalker.Insert()
+func (p *PersistentWalker) Insert(path string, index int, node *YamlWalker) (*PersistentWalker, error) {
+	parts, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	node = node.Clone()
+	return p.edit(parts, func(root *YamlWalker) error {
+		return root.insert(parts, index, node)
+	})
+}
+
+// Remove returns new version without the item at the index of the sequence at the path,
+// see YamlWalker.Remove()
+func (p *PersistentWalker) Remove(path string, index int) (*PersistentWalker, error) {
+	parts, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.edit(parts, func(root *YamlWalker) error {
+		return root.remove(parts, index)
+	})
+}
+
+// edit copies the nodes along the path and calls fn with the copy of the root.
+// fn may change the copied nodes only, i.e. the nodes on the path and the children of the last one.
+func (p *PersistentWalker) edit(parts []string, fn func(root *YamlWalker) error) (*PersistentWalker, error) {
+	anchored := make(map[*YamlWalker]*YamlWalker)
+	owned := make(map[*YamlWalker]bool)
+	root, err := p.root.copyPath(parts, anchored, owned)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(root); err != nil {
+		return nil, err
+	}
+
+	// relinking walks the whole tree, it may copy other anchored nodes, their aliases are relinked by the next pass
+	for len(anchored) > 0 {
+		next := make(map[*YamlWalker]*YamlWalker)
+		root = root.relinkAliases(anchored, owned, next)
+		anchored = next
+	}
+	return &PersistentWalker{root: root}, nil
+}
+
+// copyPath returns the copy of the root with the nodes along the path copied,
+// other nodes are shared with the original tree. Aliases on the way are replaced
+// by the copies of the anchored nodes.
+// Copied nodes are added to owned, copied nodes having an anchor are added to anchored.
+func (walker *YamlWalker) copyPath(parts []string, anchored map[*YamlWalker]*YamlWalker, owned map[*YamlWalker]bool) (*YamlWalker, error) {
+	root := walker.shallowCopy()
+	owned[root] = true
+	if len(walker.anchor) > 0 {
+		anchored[walker] = root
+	}
+
+	n := root
+	for _, part := range parts {
+		var child *YamlWalker
+		switch x := n.data.(type) {
+		case map[string]*YamlWalker:
+			c, found := x[part]
+			if !found {
+				return nil, ErrNotFound
+			}
+			child = c.copyChild(anchored, owned)
+			x[part] = child
+		case []*YamlWalker:
+			index, err := sequenceIndex(part, len(x))
+			if err != nil {
+				return nil, err
+			}
+			if index >= len(x) {
+				return nil, ErrInvalidRange
+			}
+			child = x[index].copyChild(anchored, owned)
+			x[index] = child
+		default:
+			return nil, ErrInvalidType
+		}
+		n = child
+	}
+
+	return root, nil
+}
+
+func (walker *YamlWalker) copyChild(anchored map[*YamlWalker]*YamlWalker, owned map[*YamlWalker]bool) *YamlWalker {
+	var n *YamlWalker
+	if walker.alias != nil {
+		n = walker.expandAlias()
+	} else {
+		n = walker.shallowCopy()
+		if len(walker.anchor) > 0 {
+			anchored[walker] = n
+		}
+	}
+	owned[n] = true
+	return n
+}
+
+// shallowCopy returns the copy of the node sharing the children with the original one
+func (walker *YamlWalker) shallowCopy() *YamlWalker {
+	n := *walker
+	n.keys = make([]yamlKey, len(walker.keys))
+	copy(n.keys, walker.keys)
+
+	switch x := walker.data.(type) {
+	case map[string]*YamlWalker:
+		m := make(map[string]*YamlWalker, len(x))
+		for k, v := range x {
+			m[k] = v
+		}
+		n.data = m
+	case []*YamlWalker:
+		s := make([]*YamlWalker, len(x))
+		copy(s, x)
+		n.data = s
+	}
+	return &n
+}
+
+// relinkAliases returns the node with the aliases of the nodes listed in copies referring to the copies.
+// Owned nodes are changed in place, other nodes on the way to such aliases are copied.
+// Copied nodes having an anchor are added to anchored.
+func (walker *YamlWalker) relinkAliases(copies map[*YamlWalker]*YamlWalker, owned map[*YamlWalker]bool, anchored map[*YamlWalker]*YamlWalker) *YamlWalker {
+	var n *YamlWalker
+	own := func() *YamlWalker {
+		if n == nil {
+			n = walker
+			if !owned[walker] {
+				n = walker.shallowCopy()
+				owned[n] = true
+				if len(walker.anchor) > 0 {
+					anchored[walker] = n
+				}
+			}
+		}
+		return n
+	}
+
+	if walker.alias != nil {
+		if target, found := copies[walker.alias]; found {
+			own().alias = target
+			return n
+		}
+		return walker
+	}
+
+	switch x := walker.data.(type) {
+	case map[string]*YamlWalker:
+		for k, v := range x {
+			if r := v.relinkAliases(copies, owned, anchored); r != v {
+				own().data.(map[string]*YamlWalker)[k] = r
+			}
+		}
+	case []*YamlWalker:
+		for i, v := range x {
+			if r := v.relinkAliases(copies, owned, anchored); r != v {
+				own().data.([]*YamlWalker)[i] = r
+			}
+		}
+	}
+
+	if n == nil {
+		return walker
+	}
+	return n
+}
@@ -0,0 +1,511 @@
+package yamlwalker
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidQuery = errors.New("invalid query")
+)
+
+type stepKind int
+
+const (
+	stepName stepKind = iota
+	stepWildcard
+	stepIndex
+	stepSlice
+	stepFilter
+)
+
+// queryStep selects children of every node matched by the previous step
+type queryStep struct {
+	kind      stepKind
+	recursive bool // the step applies to the nodes and all their descendants
+	name      string
+	index     int
+	start     *int
+	end       *int
+	filter    [][]*queryCondition // conditions joined by || of conditions joined by &&
+}
+
+type queryCondition struct {
+	path  Path
+	op    string
+	value interface{}
+	regex *regexp.Regexp
+}
+
+// Query returns the nodes selected by the expression.
+//
+// The expression is a path as accepted by Get() extended with:
+//   - "*" selects any child of map or sequence, e.g. "spec.containers.*.image" or "spec.containers[*].image"
+//   - "..name" searches the name in the node and all its descendants, e.g. "..image"
+//   - "[n]" selects item of sequence, negative index counts from the end
+//   - "[a:b]" selects items of sequence from a to b exclusive, a or b may be omitted or negative
+//   - "[?(cond)]" selects children satisfying the condition
+//
+// The condition compares a value at path relative to the child (@) with a literal:
+// ==, !=, <, <=, >, >= or =~ for regular expression match, e.g. [?(@.name =~ "^api")].
+// The condition without operator checks the path exists, e.g. [?(@.ports)].
+// Conditions may be joined with && and ||.
+// The expression may start with "$" denoting the node Query is called on.
+//
+// Aliases are resolved, the returned nodes are the anchored ones.
+// Names are looked up in the maps merged by the merge key "<<" the same way Get() does.
+// Nothing selected is not an error, the empty list is returned.
+// If the expression can not be parsed err set to ErrInvalidQuery.
+func (walker *YamlWalker) Query(expr string) ([]*YamlWalker, error) {
+	steps, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []*YamlWalker{walker.resolve()}
+	for _, step := range steps {
+		nodes = step.apply(nodes)
+	}
+
+	return nodes, nil
+}
+
+func (step *queryStep) apply(nodes []*YamlWalker) []*YamlWalker {
+	if step.recursive {
+		nodes = descendants(nodes)
+	}
+
+	selected := []*YamlWalker{}
+	seen := make(map[*YamlWalker]bool)
+	for _, n := range nodes {
+		for _, c := range step.selectChildren(n) {
+			if !seen[c] {
+				seen[c] = true
+				selected = append(selected, c)
+			}
+		}
+	}
+	return selected
+}
+
+func (step *queryStep) selectChildren(node *YamlWalker) []*YamlWalker {
+	switch step.kind {
+	case stepName:
+		child, err := node.lookup(step.name, nil)
+		if err != nil {
+			return nil
+		}
+		return []*YamlWalker{child}
+	case stepWildcard:
+		return children(node)
+	case stepIndex:
+		if _, ok := node.data.([]*YamlWalker); !ok {
+			return nil
+		}
+		child, err := node.child(strconv.Itoa(step.index))
+		if err != nil {
+			return nil
+		}
+		return []*YamlWalker{child}
+	case stepSlice:
+		s, ok := node.data.([]*YamlWalker)
+		if !ok {
+			return nil
+		}
+		start, end := sliceBounds(step.start, step.end, len(s))
+		selected := []*YamlWalker{}
+		for i := start; i < end; i++ {
+			selected = append(selected, s[i].resolve())
+		}
+		return selected
+	case stepFilter:
+		selected := []*YamlWalker{}
+		for _, c := range children(node) {
+			if step.match(c) {
+				selected = append(selected, c)
+			}
+		}
+		return selected
+	}
+	return nil
+}
+
+func (step *queryStep) match(node *YamlWalker) bool {
+	for _, and := range step.filter {
+		matched := true
+		for _, cond := range and {
+			if !cond.match(node) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (cond *queryCondition) match(node *YamlWalker) bool {
+	n, err := node.findNode(cond.path)
+	if err != nil {
+		return false
+	}
+
+	value := n.data
+	switch cond.op {
+	case "":
+		return true
+	case "==":
+		return compareValues(value, cond.value) == 0
+	case "!=":
+		return compareValues(value, cond.value) != 0
+	case "<":
+		return compareValues(value, cond.value) == -1
+	case "<=":
+		c := compareValues(value, cond.value)
+		return c == -1 || c == 0
+	case ">":
+		return compareValues(value, cond.value) == 1
+	case ">=":
+		c := compareValues(value, cond.value)
+		return c == 1 || c == 0
+	case "=~":
+		switch value.(type) {
+		case map[string]*YamlWalker, []*YamlWalker:
+			return false
+		}
+		s, _ := encodeValue(value)
+		return cond.regex.MatchString(s)
+	}
+	return false
+}
+
+// compareValues returns -1, 0 or 1 if a is less, equal or greater than b
+// and 2 if values are not comparable.
+func compareValues(a, b interface{}) int {
+	fa, aNum := toFloat(a)
+	fb, bNum := toFloat(b)
+	if aNum && bNum {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+
+	sa, aStr := a.(string)
+	sb, bStr := b.(string)
+	if aStr && bStr {
+		return strings.Compare(sa, sb)
+	}
+
+	switch a.(type) {
+	case map[string]*YamlWalker, []*YamlWalker:
+		return 2
+	}
+	if sameValue(a, b) {
+		return 0
+	}
+	return 2
+}
+
+// sameValue reports whether the values are equal.
+// Values of uncomparable types, e.g. slices set by SetValue(), are compared deeply instead of panicking.
+func sameValue(a, b interface{}) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if ta == nil || ta.Comparable() {
+		return a == b
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+// children returns resolved children of map in the order of keys or items of sequence
+func children(node *YamlWalker) []*YamlWalker {
+	selected := []*YamlWalker{}
+	switch x := node.data.(type) {
+	case map[string]*YamlWalker:
+		for _, k := range node.keys {
+			if child, found := x[k.name]; found {
+				selected = append(selected, child.resolve())
+			}
+		}
+	case []*YamlWalker:
+		for _, child := range x {
+			selected = append(selected, child.resolve())
+		}
+	}
+	return selected
+}
+
+// descendants returns the nodes and all their descendants in pre-order
+func descendants(nodes []*YamlWalker) []*YamlWalker {
+	all := []*YamlWalker{}
+	for _, n := range nodes {
+		_ = n.Walk(func(path Path, node *YamlWalker) error {
+			if node.alias == nil {
+				all = append(all, node)
+			}
+			return nil
+		})
+	}
+	return all
+}
+
+func sliceBounds(start, end *int, size int) (int, int) {
+	bound := func(p *int, def int) int {
+		if p == nil {
+			return def
+		}
+		i := *p
+		if i < 0 {
+			i += size
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > size {
+			return size
+		}
+		return i
+	}
+	s, e := bound(start, 0), bound(end, size)
+	if e < s {
+		e = s
+	}
+	return s, e
+}
+
+func parseQuery(expr string) ([]*queryStep, error) {
+	steps := []*queryStep{}
+	i := 0
+	if strings.HasPrefix(expr, "$") {
+		i++
+	}
+
+	for i < len(expr) {
+		step := &queryStep{}
+		switch {
+		case strings.HasPrefix(expr[i:], ".."):
+			step.recursive = true
+			i += 2
+		case expr[i] == Separator[0]:
+			i++
+		case expr[i] == '[' || len(steps) == 0:
+		default:
+			return nil, ErrInvalidQuery
+		}
+		if i == len(expr) {
+			return nil, ErrInvalidQuery
+		}
+
+		var err error
+		switch {
+		case expr[i] == '[':
+			i, err = step.parseBracket(expr, i)
+		case expr[i] == '*':
+			step.kind = stepWildcard
+			i++
+		default:
+			step.kind = stepName
+			step.name, _, i, err = parseElement(expr, i)
+		}
+		if err != nil {
+			return nil, ErrInvalidQuery
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// parseBracket parses the bracketed step started at index start
+// and returns the index following the closing bracket.
+func (step *queryStep) parseBracket(expr string, start int) (int, error) {
+	if strings.HasPrefix(expr[start:], "[?(") {
+		end := findFilterEnd(expr, start+3)
+		if end < 0 {
+			return 0, ErrInvalidQuery
+		}
+		step.kind = stepFilter
+		err := step.parseFilter(expr[start+3 : end])
+		return end + 2, err
+	}
+
+	end := strings.IndexByte(expr[start:], ']')
+	if end < 0 {
+		return 0, ErrInvalidQuery
+	}
+	content := strings.TrimSpace(expr[start+1 : start+end])
+	next := start + end + 1
+
+	switch {
+	case content == "*":
+		step.kind = stepWildcard
+	case strings.HasPrefix(content, "\"") || strings.HasPrefix(content, "'"):
+		var b strings.Builder
+		e, err := parseQuoted(content, 0, &b)
+		if err != nil || e != len(content) {
+			return 0, ErrInvalidQuery
+		}
+		step.kind = stepName
+		step.name = b.String()
+	case strings.Contains(content, ":"):
+		step.kind = stepSlice
+		bounds := strings.SplitN(content, ":", 2)
+		var err error
+		if step.start, err = parseBound(bounds[0]); err != nil {
+			return 0, err
+		}
+		if step.end, err = parseBound(bounds[1]); err != nil {
+			return 0, err
+		}
+	default:
+		index, err := strconv.Atoi(content)
+		if err != nil {
+			return 0, ErrInvalidQuery
+		}
+		step.kind = stepIndex
+		step.index = index
+	}
+
+	return next, nil
+}
+
+func parseBound(s string) (*int, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return nil, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, ErrInvalidQuery
+	}
+	return &i, nil
+}
+
+// findFilterEnd returns the index of ")]" closing the filter skipping quoted strings
+func findFilterEnd(expr string, start int) int {
+	var quote byte
+	for i := start; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case strings.HasPrefix(expr[i:], ")]"):
+			return i
+		}
+	}
+	return -1
+}
+
+func (step *queryStep) parseFilter(filter string) error {
+	for _, or := range splitOutsideQuotes(filter, "||") {
+		and := []*queryCondition{}
+		for _, c := range splitOutsideQuotes(or, "&&") {
+			cond, err := parseCondition(strings.TrimSpace(c))
+			if err != nil {
+				return err
+			}
+			and = append(and, cond)
+		}
+		step.filter = append(step.filter, and)
+	}
+	return nil
+}
+
+var queryOperators = []string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+func parseCondition(cond string) (*queryCondition, error) {
+	if !strings.HasPrefix(cond, "@") {
+		return nil, ErrInvalidQuery
+	}
+
+	left, op, right := cond[1:], "", ""
+	for _, o := range queryOperators {
+		if parts := splitOutsideQuotes(cond[1:], o); len(parts) == 2 {
+			left, op, right = parts[0], o, strings.TrimSpace(parts[1])
+			break
+		}
+	}
+
+	left = strings.TrimPrefix(strings.TrimSpace(left), Separator)
+	path, err := ParsePath(left)
+	if err != nil {
+		return nil, ErrInvalidQuery
+	}
+	c := &queryCondition{path: path, op: op}
+	if len(op) == 0 {
+		return c, nil
+	}
+
+	if err := yaml.Unmarshal([]byte(right), &c.value); err != nil || len(right) == 0 {
+		return nil, ErrInvalidQuery
+	}
+	if op == "=~" {
+		s, ok := c.value.(string)
+		if !ok {
+			return nil, ErrInvalidQuery
+		}
+		if c.regex, err = regexp.Compile(s); err != nil {
+			return nil, ErrInvalidQuery
+		}
+	}
+
+	return c, nil
+}
+
+// splitOutsideQuotes splits s by separator not enclosed in quotes
+func splitOutsideQuotes(s string, sep string) []string {
+	parts := []string{}
+	var quote byte
+	last := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case strings.HasPrefix(s[i:], sep):
+			parts = append(parts, s[last:i])
+			i += len(sep) - 1
+			last = i + 1
+		}
+	}
+	return append(parts, s[last:])
+}
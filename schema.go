@@ -0,0 +1,465 @@
+package yamlwalker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSchema = errors.New("invalid schema")
+)
+
+// Schema is a compiled JSON Schema.
+//
+// Supported keywords are the subset of draft 2020-12:
+// type, properties, required, enum, pattern, items, additionalProperties,
+// oneOf, anyOf, allOf, $ref, if, then and else. Other keywords are ignored.
+// References are local JSON Pointers only, e.g. "#/$defs/port".
+type Schema struct {
+	root *schemaNode
+}
+
+type schemaNode struct {
+	always     *bool // boolean schema
+	types      []string
+	properties map[string]*schemaNode
+	required   []string
+	enum       []*YamlWalker
+	pattern    *regexp.Regexp
+	items      *schemaNode
+	additional *schemaNode
+	oneOf      []*schemaNode
+	anyOf      []*schemaNode
+	allOf      []*schemaNode
+	ref        *schemaNode
+	ifSchema   *schemaNode
+	thenSchema *schemaNode
+	elseSchema *schemaNode
+}
+
+// ValidationError describes a single violation of the schema
+type ValidationError struct {
+	Path         Path   // path of the invalid node
+	Line, Column int    // position of the invalid node in the source, 0 for nodes not decoded
+	Keyword      string // keyword of the schema which is violated
+	Message      string
+}
+
+func (e ValidationError) Error() string {
+	if len(e.Path) == 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Path, e.Message)
+}
+
+type schemaCompiler struct {
+	root     *YamlWalker
+	compiled map[*YamlWalker]*schemaNode
+}
+
+// NewSchema compiles JSON Schema.
+// If the schema is malformed or the reference can not be resolved err is ErrInvalidSchema.
+// References forming a cycle which does not go down to a child of the validated node,
+// e.g. "$ref: '#'" at the root, are ErrInvalidSchema too as such schema would never finish the validation.
+func NewSchema(schema *YamlWalker) (*Schema, error) {
+	c := &schemaCompiler{
+		root:     schema.resolve(),
+		compiled: make(map[*YamlWalker]*schemaNode),
+	}
+	root, err := c.compile(c.root, Path{})
+	if err != nil {
+		return nil, err
+	}
+
+	visiting := make(map[*schemaNode]bool)
+	done := make(map[*schemaNode]bool)
+	for _, s := range c.compiled {
+		if err := s.checkCycle(visiting, done); err != nil {
+			return nil, err
+		}
+	}
+	return &Schema{root: root}, nil
+}
+
+// checkCycle returns ErrInvalidSchema if the schema reaches itself through the schemas
+// applied to the same node, i.e. $ref, allOf, anyOf, oneOf, if, then and else.
+func (s *schemaNode) checkCycle(visiting, done map[*schemaNode]bool) error {
+	if done[s] {
+		return nil
+	}
+	if visiting[s] {
+		return fmt.Errorf("%w: reference cycle not consuming any value", ErrInvalidSchema)
+	}
+
+	visiting[s] = true
+	next := []*schemaNode{s.ref, s.ifSchema, s.thenSchema, s.elseSchema}
+	next = append(next, s.allOf...)
+	next = append(next, s.anyOf...)
+	next = append(next, s.oneOf...)
+	for _, n := range next {
+		if n == nil {
+			continue
+		}
+		if err := n.checkCycle(visiting, done); err != nil {
+			return err
+		}
+	}
+	visiting[s] = false
+	done[s] = true
+	return nil
+}
+
+func (c *schemaCompiler) compile(node *YamlWalker, path Path) (*schemaNode, error) {
+	node = node.resolve()
+	if s, found := c.compiled[node]; found {
+		return s, nil
+	}
+
+	s := &schemaNode{}
+	c.compiled[node] = s
+
+	switch x := node.data.(type) {
+	case bool:
+		s.always = &x
+		return s, nil
+	case map[string]*YamlWalker:
+	default:
+		return nil, schemaError(path, "schema must be a map or a boolean")
+	}
+
+	for _, k := range node.keys {
+		value, err := node.child(k.name)
+		if err != nil {
+			return nil, err
+		}
+		p := path.Append(k.name)
+		switch k.name {
+		case "type":
+			s.types, err = schemaTypes(value, p)
+		case "properties":
+			err = c.compileProperties(s, value, p)
+		case "required":
+			s.required, err = schemaStrings(value, p)
+		case "enum":
+			items, ok := value.data.([]*YamlWalker)
+			if !ok {
+				return nil, schemaError(p, "must be a sequence")
+			}
+			s.enum = items
+		case "pattern":
+			pattern, ok := value.data.(string)
+			if !ok {
+				return nil, schemaError(p, "must be a string")
+			}
+			if s.pattern, err = regexp.Compile(pattern); err != nil {
+				return nil, schemaError(p, err.Error())
+			}
+		case "items":
+			s.items, err = c.compile(value, p)
+		case "additionalProperties":
+			s.additional, err = c.compile(value, p)
+		case "oneOf":
+			s.oneOf, err = c.compileList(value, p)
+		case "anyOf":
+			s.anyOf, err = c.compileList(value, p)
+		case "allOf":
+			s.allOf, err = c.compileList(value, p)
+		case "$ref":
+			s.ref, err = c.compileRef(value, p)
+		case "if":
+			s.ifSchema, err = c.compile(value, p)
+		case "then":
+			s.thenSchema, err = c.compile(value, p)
+		case "else":
+			s.elseSchema, err = c.compile(value, p)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (c *schemaCompiler) compileProperties(s *schemaNode, node *YamlWalker, path Path) error {
+	m, ok := node.data.(map[string]*YamlWalker)
+	if !ok {
+		return schemaError(path, "must be a map")
+	}
+
+	s.properties = make(map[string]*schemaNode, len(m))
+	for _, k := range node.keys {
+		property, err := c.compile(m[k.name], path.Append(k.name))
+		if err != nil {
+			return err
+		}
+		s.properties[k.name] = property
+	}
+	return nil
+}
+
+func (c *schemaCompiler) compileList(node *YamlWalker, path Path) ([]*schemaNode, error) {
+	items, ok := node.data.([]*YamlWalker)
+	if !ok || len(items) == 0 {
+		return nil, schemaError(path, "must be a non empty sequence")
+	}
+
+	list := make([]*schemaNode, len(items))
+	for i, item := range items {
+		s, err := c.compile(item, path.Append(Index(i)))
+		if err != nil {
+			return nil, err
+		}
+		list[i] = s
+	}
+	return list, nil
+}
+
+func (c *schemaCompiler) compileRef(node *YamlWalker, path Path) (*schemaNode, error) {
+	ref, ok := node.data.(string)
+	if !ok || !strings.HasPrefix(ref, "#") {
+		return nil, schemaError(path, "must be a local reference starting with '#'")
+	}
+
+	pointer, err := parsePointer(ref[1:])
+	if err != nil {
+		return nil, schemaError(path, err.Error())
+	}
+	target, err := c.root.pointerGet(pointer)
+	if err != nil {
+		return nil, schemaError(path, fmt.Sprintf("unresolved reference '%s'", ref))
+	}
+	return c.compile(target, pointer)
+}
+
+func schemaTypes(node *YamlWalker, path Path) ([]string, error) {
+	if name, ok := node.data.(string); ok {
+		node = &YamlWalker{data: []*YamlWalker{{data: name}}}
+	}
+	types, err := schemaStrings(node, path)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		switch t {
+		case "null", "boolean", "object", "array", "number", "integer", "string":
+		default:
+			return nil, schemaError(path, fmt.Sprintf("unknown type '%s'", t))
+		}
+	}
+	return types, nil
+}
+
+func schemaStrings(node *YamlWalker, path Path) ([]string, error) {
+	items, ok := node.data.([]*YamlWalker)
+	if !ok {
+		return nil, schemaError(path, "must be a sequence of strings")
+	}
+
+	strs := make([]string, len(items))
+	for i, item := range items {
+		s, ok := item.resolve().data.(string)
+		if !ok {
+			return nil, schemaError(path, "must be a sequence of strings")
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+func schemaError(path Path, message string) error {
+	return fmt.Errorf("%w: %s: %s", ErrInvalidSchema, path, message)
+}
+
+// Validate checks the document against the schema and returns all violations.
+// Keys merged by the merge key "<<" are validated as the keys of the map.
+// Valid document returns no errors.
+func (schema *Schema) Validate(document *YamlWalker) []ValidationError {
+	return schema.root.validate(document, Path{})
+}
+
+// Validate checks the node against the schema, see Schema.Validate()
+func (walker *YamlWalker) Validate(schema *Schema) []ValidationError {
+	return schema.Validate(walker)
+}
+
+// validate returns the violations of the node.
+// The node may be an alias, the errors refer to its position.
+func (s *schemaNode) validate(node *YamlWalker, path Path) []ValidationError {
+	if s.always != nil {
+		if *s.always {
+			return nil
+		}
+		return []ValidationError{newValidationError(node, path, "false", "no value is allowed")}
+	}
+
+	errs := []ValidationError{}
+	fail := func(keyword, message string) {
+		errs = append(errs, newValidationError(node, path, keyword, message))
+	}
+
+	value := node.resolve()
+	if s.ref != nil {
+		errs = append(errs, s.ref.validate(node, path)...)
+	}
+
+	if len(s.types) > 0 && !matchTypes(value, s.types) {
+		fail("type", fmt.Sprintf("must be %s, got %s", strings.Join(s.types, " or "), valueType(value)))
+	}
+
+	if len(s.enum) > 0 {
+		found := false
+		for _, e := range s.enum {
+			if value.equal(e, sameValue) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("enum", "must be one of the enum values")
+		}
+	}
+
+	if s.pattern != nil {
+		if str, ok := stringValue(value); ok && !s.pattern.MatchString(str) {
+			fail("pattern", fmt.Sprintf("must match pattern '%s'", s.pattern))
+		}
+	}
+
+	switch x := value.data.(type) {
+	case map[string]*YamlWalker:
+		keys, children := value.merged(nil)
+		for _, name := range s.required {
+			if _, found := children[name]; !found {
+				fail("required", fmt.Sprintf("missing required property '%s'", name))
+			}
+		}
+		for _, k := range keys {
+			child := children[k.name]
+			if property, found := s.properties[k.name]; found {
+				errs = append(errs, property.validate(child, path.Append(k.name))...)
+				continue
+			}
+			if s.additional != nil {
+				if s.additional.always != nil && !*s.additional.always {
+					e := newValidationError(child, path.Append(k.name), "additionalProperties",
+						fmt.Sprintf("property '%s' is not allowed", k.name))
+					if k.position.Line > 0 {
+						// point at the key rather than at the value
+						e.Line, e.Column = k.position.Line, k.position.Column
+					}
+					errs = append(errs, e)
+					continue
+				}
+				errs = append(errs, s.additional.validate(child, path.Append(k.name))...)
+			}
+		}
+	case []*YamlWalker:
+		if s.items != nil {
+			for i, item := range x {
+				errs = append(errs, s.items.validate(item, path.Append(Index(i)))...)
+			}
+		}
+	}
+
+	for _, sub := range s.allOf {
+		errs = append(errs, sub.validate(node, path)...)
+	}
+	if len(s.anyOf) > 0 && countValid(s.anyOf, node, path) == 0 {
+		fail("anyOf", "must match at least one schema of anyOf")
+	}
+	if len(s.oneOf) > 0 {
+		if matched := countValid(s.oneOf, node, path); matched != 1 {
+			fail("oneOf", fmt.Sprintf("must match exactly one schema of oneOf, matched %d", matched))
+		}
+	}
+
+	if s.ifSchema != nil {
+		if len(s.ifSchema.validate(node, path)) == 0 {
+			if s.thenSchema != nil {
+				errs = append(errs, s.thenSchema.validate(node, path)...)
+			}
+		} else if s.elseSchema != nil {
+			errs = append(errs, s.elseSchema.validate(node, path)...)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func newValidationError(node *YamlWalker, path Path, keyword, message string) ValidationError {
+	return ValidationError{
+		Path:    path,
+		Line:    node.position.Line,
+		Column:  node.position.Column,
+		Keyword: keyword,
+		Message: message,
+	}
+}
+
+func countValid(schemas []*schemaNode, node *YamlWalker, path Path) int {
+	count := 0
+	for _, s := range schemas {
+		if len(s.validate(node, path)) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func matchTypes(node *YamlWalker, types []string) bool {
+	actual := valueType(node)
+	for _, t := range types {
+		switch {
+		case t == actual:
+			return true
+		case t == "number" && actual == "integer":
+			return true
+		case t == "integer" && actual == "number":
+			if f, ok := toFloat(node.data); ok && f == math.Trunc(f) && !math.IsInf(f, 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// valueType returns JSON Schema type of the node
+func valueType(node *YamlWalker) string {
+	switch node.data.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]*YamlWalker:
+		return "object"
+	case []*YamlWalker:
+		return "array"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		return "number"
+	}
+	return "string"
+}
+
+// stringValue returns the value of the string node, timestamps are taken as written in the source
+func stringValue(node *YamlWalker) (string, bool) {
+	switch x := node.data.(type) {
+	case string:
+		return x, true
+	case time.Time:
+		if node.raw != nil {
+			return *node.raw, true
+		}
+		return x.Format(time.RFC3339Nano), true
+	}
+	return "", false
+}
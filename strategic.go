@@ -0,0 +1,309 @@
+package yamlwalker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	patchDirective        = "$patch"
+	setElementOrderPrefix = "$setElementOrder/"
+)
+
+// StrategicMergeOption configures ApplyStrategicMergePatch()
+type StrategicMergeOption func(*strategicOptions)
+
+type strategicOptions struct {
+	keys []sequenceRule
+	err  error // the first invalid pattern
+}
+
+// WithMergeKey merges the items of the sequence at the path by the value of the key field,
+// e.g. WithMergeKey("spec.template.spec.containers", "name").
+// The path is a pattern, element "*" matches any key or index, e.g. "spec.containers.*.env".
+// Empty key merges the sequence of scalars as a set.
+// Invalid pattern makes ApplyStrategicMergePatch() fail with ErrInvalidPath.
+func WithMergeKey(path string, key string) StrategicMergeOption {
+	return func(o *strategicOptions) {
+		pattern, err := parsePattern(path)
+		if err != nil {
+			if o.err == nil {
+				o.err = err
+			}
+			return
+		}
+		o.keys = append(o.keys, sequenceRule{pattern: pattern, strategy: SequenceMergeByKey, key: key})
+	}
+}
+
+// ApplyStrategicMergePatch applies Kubernetes style strategic merge patch to the tree.
+//
+// It works as ApplyMergePatch() except for sequences having the merge key, see WithMergeKey().
+// Items of such sequences are merged with the existing items having the same value of the key field,
+// items without matching one are appended. Other sequences are replaced as a whole.
+//
+// The patch may include the directives:
+//   - "$patch: delete" in a map deletes the map, in an item of sequence deletes the matching item;
+//     the root of the document can not be deleted
+//   - "$patch: replace" in a map replaces the map instead of merging it,
+//     the item {"$patch": "replace"} of sequence replaces the sequence by other items of the patch
+//   - "$setElementOrder/name: [...]" orders the items of the sequence "name", the list holds
+//     the values of the merge key or maps with the merge key only; items not listed keep their positions
+//
+// Existing keys and items keep their position, style and comments.
+// The patch is applied atomically: if it fails the tree is left untouched.
+// If the directive is invalid err is ErrInvalidPatch, if the pattern of an option is invalid err wraps ErrInvalidPath.
+func (walker *YamlWalker) ApplyStrategicMergePatch(patch *YamlWalker, opts ...StrategicMergeOption) error {
+	options := &strategicOptions{}
+	for _, o := range opts {
+		o(options)
+	}
+	if options.err != nil {
+		return options.err
+	}
+
+	patch = patch.resolve()
+	directive, err := patchDirectiveOf(patch)
+	if err != nil {
+		return err
+	}
+	if directive == "delete" {
+		return fmt.Errorf("%w: %s delete can not be applied to the root", ErrInvalidPatch, patchDirective)
+	}
+
+	if err := walker.deepCopy().strategicMerge(patch, Path{}, options); err != nil {
+		return err
+	}
+	return walker.strategicMerge(patch, Path{}, options)
+}
+
+func (walker *YamlWalker) strategicMerge(patch *YamlWalker, path Path, options *strategicOptions) error {
+	switch x := patch.data.(type) {
+	case map[string]*YamlWalker:
+		directive, err := patchDirectiveOf(patch)
+		if err != nil {
+			return err
+		}
+		if _, ok := walker.data.(map[string]*YamlWalker); ok && directive != "replace" {
+			return walker.strategicMergeMap(patch, x, path, options)
+		}
+	case []*YamlWalker:
+		if s, ok := walker.data.([]*YamlWalker); ok {
+			if rule, found := matchRule(options.keys, path); found && !hasReplaceDirective(x) {
+				return walker.strategicMergeSeq(s, x, rule.key, path, options)
+			}
+		}
+	default:
+		walker.assign(patch.deepCopy())
+		return nil
+	}
+
+	node := patch.deepCopy()
+	node.removeDirectives()
+	walker.replaceWith(node)
+	return nil
+}
+
+func (walker *YamlWalker) strategicMergeMap(patch *YamlWalker, x map[string]*YamlWalker, path Path, options *strategicOptions) error {
+	keys := make([]yamlKey, 0, len(patch.keys))
+	orders := []yamlKey{}
+	for _, k := range patch.keys {
+		switch {
+		case k.name == patchDirective:
+		case strings.HasPrefix(k.name, setElementOrderPrefix):
+			orders = append(orders, k)
+		case strings.HasPrefix(k.name, "$"):
+			return fmt.Errorf("%w: unsupported directive '%s'", ErrInvalidPatch, k.name)
+		default:
+			keys = append(keys, k)
+		}
+	}
+
+	err := walker.mergeEntries(patch, keys, entryMerge{
+		remove: func(value *YamlWalker) (bool, error) {
+			directive, err := patchDirectiveOf(value)
+			return value.isNull() || directive == "delete", err
+		},
+		add: func(value *YamlWalker) *YamlWalker {
+			node := value.deepCopy()
+			node.removeDirectives()
+			return node
+		},
+		merge: func(name string, existing, value *YamlWalker) error {
+			return existing.strategicMerge(value, path.Append(name), options)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	m := walker.data.(map[string]*YamlWalker)
+	for _, k := range orders {
+		name := strings.TrimPrefix(k.name, setElementOrderPrefix)
+		order, ok := x[k.name].resolve().data.([]*YamlWalker)
+		if !ok {
+			return fmt.Errorf("%w: '%s' must be a sequence", ErrInvalidPatch, k.name)
+		}
+		existing, found := m[name]
+		if !found {
+			continue
+		}
+		if existing.alias != nil {
+			existing = existing.expandAlias()
+			m[name] = existing
+		}
+		rule, _ := matchRule(options.keys, path.Append(name))
+		existing.setElementOrder(order, rule.key)
+	}
+
+	return nil
+}
+
+func (walker *YamlWalker) strategicMergeSeq(s, x []*YamlWalker, key string, path Path, options *strategicOptions) error {
+	for _, v := range x {
+		v = v.resolve()
+		directive, err := patchDirectiveOf(v)
+		if err != nil {
+			return err
+		}
+
+		index := findPatchItem(s, v, key)
+		if directive == "delete" {
+			if index >= 0 {
+				s = append(s[:index], s[index+1:]...)
+			}
+			continue
+		}
+		if index < 0 {
+			node := v.deepCopy()
+			node.removeDirectives()
+			s = append(s, node)
+			continue
+		}
+		if !v.isMap() {
+			continue
+		}
+
+		existing := s[index]
+		if existing.alias != nil {
+			existing = existing.expandAlias()
+			s[index] = existing
+		}
+		if err := existing.strategicMerge(v, path.Append(strconv.Itoa(index)), options); err != nil {
+			return err
+		}
+	}
+
+	walker.data = s
+	return nil
+}
+
+// setElementOrder places the items listed in the order to the positions occupied by the listed items.
+// Items not listed keep their positions.
+func (walker *YamlWalker) setElementOrder(order []*YamlWalker, key string) {
+	s, ok := walker.data.([]*YamlWalker)
+	if !ok {
+		return
+	}
+
+	listed := make(map[int]bool, len(order))
+	items := []*YamlWalker{}
+	for _, v := range order {
+		v = v.resolve()
+		var index int
+		if len(key) > 0 && v.isScalar() {
+			// the order lists the values of the merge key
+			index = findByKeyValue(s, v, key)
+		} else {
+			index = findPatchItem(s, v, key)
+		}
+		if index >= 0 && !listed[index] {
+			listed[index] = true
+			items = append(items, s[index])
+		}
+	}
+
+	sorted := make([]*YamlWalker, len(s))
+	next := 0
+	for i := range s {
+		if listed[i] {
+			sorted[i] = items[next]
+			next++
+		} else {
+			sorted[i] = s[i]
+		}
+	}
+	walker.data = sorted
+}
+
+// findPatchItem returns the index of the item of s matching the patch item
+// by the value of the key field or by the value itself for empty key, -1 if there is no such item.
+func findPatchItem(s []*YamlWalker, item *YamlWalker, key string) int {
+	if len(key) > 0 {
+		return findByKey(s, item, key)
+	}
+	for i, v := range s {
+		if v.equal(item, sameValue) {
+			return i
+		}
+	}
+	return -1
+}
+
+// patchDirectiveOf returns the value of $patch directive of the map
+func patchDirectiveOf(node *YamlWalker) (string, error) {
+	m, ok := node.data.(map[string]*YamlWalker)
+	if !ok {
+		return "", nil
+	}
+	value, found := m[patchDirective]
+	if !found {
+		return "", nil
+	}
+
+	directive, ok := value.resolve().data.(string)
+	switch {
+	case !ok:
+	case directive == "delete", directive == "replace", directive == "merge":
+		return directive, nil
+	}
+	return "", fmt.Errorf("%w: unsupported %s value '%v'", ErrInvalidPatch, patchDirective, value.resolve().data)
+}
+
+func hasReplaceDirective(s []*YamlWalker) bool {
+	for _, v := range s {
+		if directive, _ := patchDirectiveOf(v.resolve()); directive == "replace" {
+			return true
+		}
+	}
+	return false
+}
+
+// removeDirectives deletes directives and keys with null values from maps
+// and items holding directives only from sequences of the new node
+func (walker *YamlWalker) removeDirectives() {
+	switch x := walker.data.(type) {
+	case map[string]*YamlWalker:
+		keys := make([]yamlKey, 0, len(walker.keys))
+		for _, k := range walker.keys {
+			if strings.HasPrefix(k.name, "$") || x[k.name].resolve().isNull() {
+				delete(x, k.name)
+				continue
+			}
+			x[k.name].removeDirectives()
+			keys = append(keys, k)
+		}
+		walker.keys = keys
+	case []*YamlWalker:
+		items := make([]*YamlWalker, 0, len(x))
+		for _, v := range x {
+			if directive, _ := patchDirectiveOf(v.resolve()); directive == "delete" || (directive == "replace" && len(v.resolve().keys) == 1) {
+				continue
+			}
+			v.removeDirectives()
+			items = append(items, v)
+		}
+		walker.data = items
+	}
+}
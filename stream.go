@@ -0,0 +1,215 @@
+package yamlwalker
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YamlStream is a list of YAML documents separated by document start markers "---"
+type YamlStream struct {
+	documents []*yamlDocument
+	head      string // comment preceding the first document start marker
+	marker    bool   // the first document starts with explicit marker
+}
+
+type yamlDocument struct {
+	root    *YamlWalker
+	comment Comment
+}
+
+// NewYamlStream creates new YamlStream instance containing the documents
+func NewYamlStream(documents ...*YamlWalker) *YamlStream {
+	stream := &YamlStream{
+		documents: make([]*yamlDocument, 0, len(documents)),
+	}
+	for _, d := range documents {
+		stream.Append(d)
+	}
+	return stream
+}
+
+// DecodeAll reads all documents from r.
+// Each document gets its own YamlWalker, anchors are resolved within the document.
+// Document comments and the marker of the first document are kept to be written back by EncodeAll().
+// Repeated keys of maps are decoded according to the policy, default policy is DuplicateKeyError.
+func DecodeAll(r io.Reader, policy ...DuplicateKeyPolicy) (*YamlStream, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := NewYamlStream()
+	stream.head, stream.marker = scanStreamHead(data)
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(stream.documents) == 0 && len(stream.head) > 0 {
+			trimHeadComment(&node, stream.head)
+		}
+
+		root := NewYamlWalker()
+		if len(node.Content) > 0 {
+			state := newDecodeState()
+			if len(policy) > 0 {
+				state.duplicates = policy[0]
+			}
+			root, err = root.decode(node.Content[0], state)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		stream.documents = append(stream.documents, &yamlDocument{
+			root: root,
+			comment: Comment{
+				Head: node.HeadComment,
+				Line: node.LineComment,
+				Foot: node.FootComment,
+			},
+		})
+	}
+
+	return stream, nil
+}
+
+// EncodeAll writes all documents to w in order
+func (stream *YamlStream) EncodeAll(w io.Writer) error {
+	if stream.marker && len(stream.documents) > 0 {
+		head := ""
+		if len(stream.head) > 0 {
+			head = stream.head + "\n"
+		}
+		if _, err := io.WriteString(w, head+"---\n"); err != nil {
+			return err
+		}
+	}
+
+	encoder := yaml.NewEncoder(w)
+	for _, d := range stream.documents {
+		content, err := d.root.encodeDocument()
+		if err != nil {
+			return err
+		}
+		node := &yaml.Node{
+			Kind:        yaml.DocumentNode,
+			Content:     []*yaml.Node{content},
+			HeadComment: d.comment.Head,
+			LineComment: d.comment.Line,
+			FootComment: d.comment.Foot,
+		}
+		if err := encoder.Encode(node); err != nil {
+			return err
+		}
+	}
+
+	return encoder.Close()
+}
+
+// Len returns the number of documents in the stream
+func (stream *YamlStream) Len() int {
+	return len(stream.documents)
+}
+
+// Documents returns the list of documents in order.
+// The slice is a copy, use Append() or Remove() to change the list of documents.
+func (stream *YamlStream) Documents() []*YamlWalker {
+	documents := make([]*YamlWalker, len(stream.documents))
+	for i, d := range stream.documents {
+		documents[i] = d.root
+	}
+	return documents
+}
+
+// Document returns the document at the index.
+// If index is out of bounds err set to ErrInvalidRange.
+func (stream *YamlStream) Document(index int) (*YamlWalker, error) {
+	if index < 0 || index >= len(stream.documents) {
+		return nil, ErrInvalidRange
+	}
+	return stream.documents[index].root, nil
+}
+
+// Append appends the document at the end of the stream
+func (stream *YamlStream) Append(document *YamlWalker) {
+	stream.documents = append(stream.documents, &yamlDocument{root: document})
+}
+
+// Remove removes the document at the index.
+// If index is out of bounds err set to ErrInvalidRange.
+func (stream *YamlStream) Remove(index int) error {
+	if index < 0 || index >= len(stream.documents) {
+		return ErrInvalidRange
+	}
+	stream.documents = append(stream.documents[:index], stream.documents[index+1:]...)
+	return nil
+}
+
+// DocumentComment returns comments of the document at the index
+func (stream *YamlStream) DocumentComment(index int) (Comment, error) {
+	if index < 0 || index >= len(stream.documents) {
+		return Comment{}, ErrInvalidRange
+	}
+	return stream.documents[index].comment, nil
+}
+
+// SetDocumentComment sets comments of the document at the index
+func (stream *YamlStream) SetDocumentComment(index int, comment Comment) error {
+	if index < 0 || index >= len(stream.documents) {
+		return ErrInvalidRange
+	}
+	stream.documents[index].comment = comment
+	return nil
+}
+
+// scanStreamHead looks for the explicit start marker of the first document
+// and returns comments preceding it.
+func scanStreamHead(data []byte) (head string, marker bool) {
+	lines := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		switch {
+		case line == "---" || strings.HasPrefix(line, "--- "):
+			marker = true
+			head = strings.Join(lines, "\n")
+			return
+		case strings.HasPrefix(line, "#"):
+			lines = append(lines, line)
+		case len(line) == 0 || strings.HasPrefix(line, "%"):
+		default:
+			return
+		}
+	}
+	return
+}
+
+// trimHeadComment removes the comment preceding the first document start marker
+// from the first node yaml parser attached it to.
+func trimHeadComment(node *yaml.Node, head string) bool {
+	if len(node.HeadComment) > 0 {
+		if strings.HasPrefix(node.HeadComment, head) {
+			node.HeadComment = strings.TrimLeft(strings.TrimPrefix(node.HeadComment, head), "\n")
+		}
+		return true
+	}
+	for _, c := range node.Content {
+		if trimHeadComment(c, head) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package yamlwalker
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SyncWalker is a tree safe for concurrent use by multiple goroutines.
+//
+// Reads take the read lock, so they run in parallel and see either all or nothing of a write.
+// Writes take the write lock, every write method is atomic.
+// Nodes are never shared with the caller: nodes passed in are copied
+// and nodes returned are copies, see Clone().
+// Use Edit() to apply several changes atomically and Snapshot() to get a consistent view of the whole tree.
+// The zero value is an empty tree ready to use.
+type SyncWalker struct {
+	mu   sync.RWMutex
+	root *YamlWalker
+}
+
+// NewSyncWalker creates new SyncWalker holding the copy of the tree
+func NewSyncWalker(root *YamlWalker) *SyncWalker {
+	return &SyncWalker{root: root.Clone()}
+}
+
+// UnmarshalYAML decodes YAML and replaces the tree atomically
+func (s *SyncWalker) UnmarshalYAML(value *yaml.Node) error {
+	root := NewYamlWalker()
+	if err := root.UnmarshalYAML(value); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.root = root
+	return nil
+}
+
+// MarshalYAML encodes the tree to YAML
+func (s *SyncWalker) MarshalYAML() (interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree().MarshalYAML()
+}
+
+// Snapshot returns the copy of the whole tree, later writes do not affect it
+func (s *SyncWalker) Snapshot() *YamlWalker {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree().Clone()
+}
+
+// Get returns the copy of the node specified by path, see YamlWalker.Get()
+func (s *SyncWalker) Get(path string) (*YamlWalker, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	node, err := s.tree().Get(path)
+	if err != nil {
+		return nil, err
+	}
+	return node.Clone(), nil
+}
+
+// GetValue returns the value of the node specified by path or <nil> if node does not exists.
+// Children of maps and sequences are copies, see YamlWalker.GetValue().
+func (s *SyncWalker) GetValue(path string) interface{} {
+	node, err := s.Get(path)
+	if err != nil {
+		return nil
+	}
+	return node.Value()
+}
+
+// Keys returns key names of the map specified by path in document order, see YamlWalker.Keys()
+func (s *SyncWalker) Keys(path string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree().Keys(path)
+}
+
+// AsString returns the value of the node specified by path as string, see YamlWalker.AsString()
+func (s *SyncWalker) AsString(path string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree().AsString(path)
+}
+
+// AsInt returns the value of the node specified by path as int, see YamlWalker.AsInt()
+func (s *SyncWalker) AsInt(path string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree().AsInt(path)
+}
+
+// AsBool returns the value of the node specified by path as bool, see YamlWalker.AsBool()
+func (s *SyncWalker) AsBool(path string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree().AsBool(path)
+}
+
+// AsFloat returns the value of the node specified by path as float64, see YamlWalker.AsFloat()
+func (s *SyncWalker) AsFloat(path string) (float64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree().AsFloat(path)
+}
+
+// AsTime returns the value of the node specified by path as time.Time, see YamlWalker.AsTime()
+func (s *SyncWalker) AsTime(path string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree().AsTime(path)
+}
+
+// Decode decodes the node specified by path into out, see YamlWalker.Decode()
+func (s *SyncWalker) Decode(path string, out interface{}) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree().Decode(path, out)
+}
+
+// SetValue sets the value of the node at the specified path, see YamlWalker.SetValue().
+// If path does not exists it silently does nothing.
+func (s *SyncWalker) SetValue(path string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureRoot()
+	s.root.SetValue(path, value)
+}
+
+// Set sets the node at the specified path to the copy of node, see YamlWalker.Set()
+func (s *SyncWalker) Set(path string, node *YamlWalker) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureRoot()
+	return s.root.Set(path, node)
+}
+
+// SetPath sets the value of the node at the specified path creating all missing nodes on the way,
+// see YamlWalker.SetPath(). The node passed as value is copied.
+func (s *SyncWalker) SetPath(path string, value interface{}, opts ...SetPathOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureRoot()
+	return s.root.SetPath(path, value, opts...)
+}
+
+// Append appends the copy of node to the map at the path, see YamlWalker.Append()
+func (s *SyncWalker) Append(path string, node *YamlWalker, keyStyle ...yaml.Style) error {
+	node = node.Clone()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureRoot()
+	return s.root.Append(path, node, keyStyle...)
+}
+
+// Delete deletes the node at the path, see YamlWalker.Delete()
+func (s *SyncWalker) Delete(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureRoot()
+	return s.root.Delete(path)
+}
+
+// Insert inserts the copy of node into the sequence at the path, see YamlWalker.Insert()
+func (s *SyncWalker) Insert(path string, index int, node *YamlWalker) error {
+	node = node.Clone()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureRoot()
+	return s.root.Insert(path, index, node)
+}
+
+// Remove removes the item at the index from the sequence at the path, see YamlWalker.Remove()
+func (s *SyncWalker) Remove(path string, index int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureRoot()
+	return s.root.Remove(path, index)
+}
+
+// Edit calls fn with the copy of the tree and replaces the tree by the copy if fn returns no error,
+// so that several changes are applied atomically and readers never see the partial ones.
+// If fn fails the tree is left untouched and the error is returned.
+// The tree passed to fn must not be used after fn returns.
+func (s *SyncWalker) Edit(fn func(root *YamlWalker) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	root := s.tree().Clone()
+	if err := fn(root); err != nil {
+		return err
+	}
+	s.root = root
+	return nil
+}
+
+// tree returns the tree to read, the zero SyncWalker reads as the empty tree
+func (s *SyncWalker) tree() *YamlWalker {
+	if s.root == nil {
+		return NewYamlWalker()
+	}
+	return s.root
+}
+
+// ensureRoot creates the empty tree of the zero SyncWalker to be written, the caller holds the write lock
+func (s *SyncWalker) ensureRoot() {
+	if s.root == nil {
+		s.root = NewYamlWalker()
+	}
+}
@@ -0,0 +1,81 @@
+package yamlwalker
+
+import (
+	"errors"
+	"strconv"
+)
+
+var (
+	// SkipChildren is returned by WalkFunc to skip children of the current node.
+	// It is not an error, Walk() continues with the next sibling.
+	SkipChildren = errors.New("skip children")
+	// Stop is returned by WalkFunc to stop walking.
+	// It is not an error, Walk() returns nil.
+	Stop = errors.New("stop walking")
+)
+
+// WalkFunc is the function called for each node visited by Walk() and WalkPostOrder().
+// The path is the full path to the node from the node Walk() is called on,
+// map children are addressed by key names and sequence items by indexes.
+// The path is not reused, it is safe to keep it.
+// If the function returns an error other than SkipChildren or Stop walking stops and the error is returned.
+type WalkFunc func(path Path, node *YamlWalker) error
+
+// Walk visits the node and all its children depth-first calling fn for each node,
+// the parent is visited before its children (pre-order).
+// Children of map are visited in the order of keys and items of sequence in the order of indexes.
+// Aliases are visited as they are, the anchored nodes are not visited through aliases.
+func (walker *YamlWalker) Walk(fn WalkFunc) error {
+	err := walker.walk(Path{}, fn, false)
+	if errors.Is(err, Stop) || errors.Is(err, SkipChildren) {
+		return nil
+	}
+	return err
+}
+
+// WalkPostOrder is the same as Walk() but children are visited before their parent (post-order).
+// SkipChildren has no effect when returned by fn.
+func (walker *YamlWalker) WalkPostOrder(fn WalkFunc) error {
+	err := walker.walk(Path{}, fn, true)
+	if errors.Is(err, Stop) {
+		return nil
+	}
+	return err
+}
+
+func (walker *YamlWalker) walk(path Path, fn WalkFunc, postOrder bool) error {
+	if !postOrder {
+		if err := fn(path, walker); err != nil {
+			return err
+		}
+	}
+
+	if walker.alias == nil {
+		switch x := walker.data.(type) {
+		case map[string]*YamlWalker:
+			for _, k := range walker.keys {
+				child, found := x[k.name]
+				if !found {
+					return ErrKeyMismatch
+				}
+				if err := child.walk(path.Append(k.name), fn, postOrder); err != nil && !errors.Is(err, SkipChildren) {
+					return err
+				}
+			}
+		case []*YamlWalker:
+			for i, child := range x {
+				if err := child.walk(path.Append(strconv.Itoa(i)), fn, postOrder); err != nil && !errors.Is(err, SkipChildren) {
+					return err
+				}
+			}
+		}
+	}
+
+	if postOrder {
+		if err := fn(path, walker); err != nil && !errors.Is(err, SkipChildren) {
+			return err
+		}
+	}
+
+	return nil
+}
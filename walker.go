@@ -1,32 +1,56 @@
 package yamlwalker
 
 import (
+	"errors"
 	"fmt"
-	"strings"
+	"sort"
+	"strconv"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-func (walker *YamlWalker) splitPath(path string) []string {
-	parts := []string{}
-	if len(path) > 0 {
-		parts = strings.Split(path, Separator)
+func (walker *YamlWalker) asMap(parts []string) (children map[string]*YamlWalker, err error) {
+	w, err := walker.findNode(parts)
+	if err != nil {
+		return
+	}
+
+	m, ok := w.data.(map[string]*YamlWalker)
+	if !ok {
+		err = ErrInvalidType
+		return
 	}
-	return parts
+	children = m
+	return
 }
 
-func (walker *YamlWalker) asMap(parts []string) (children map[string]*YamlWalker, err error) {
+func (walker *YamlWalker) entries(parts []string) (entries []Entry, err error) {
 	w, err := walker.findNode(parts)
 	if err != nil {
 		return
 	}
+	w = w.resolve()
 
 	m, ok := w.data.(map[string]*YamlWalker)
 	if !ok {
 		err = ErrInvalidType
 		return
 	}
-	children = m
+
+	entries = make([]Entry, 0, len(w.keys))
+	for _, k := range w.keys {
+		node, found := m[k.name]
+		if !found {
+			return nil, ErrKeyMismatch
+		}
+		entry := Entry{Key: k.name, KeyStyle: k.style, Node: node.resolve()}
+		if k.node != nil {
+			entry.KeyNode = k.node.deepCopy()
+		}
+		entries = append(entries, entry)
+	}
+
 	return
 }
 
@@ -90,6 +114,36 @@ func (walker *YamlWalker) asBool(parts []string) (value bool, err error) {
 	return
 }
 
+func (walker *YamlWalker) asFloat(parts []string) (value float64, err error) {
+	w, err := walker.findNode(parts)
+	if err != nil {
+		return
+	}
+
+	s, ok := w.data.(float64)
+	if !ok {
+		err = ErrInvalidType
+		return
+	}
+	value = s
+	return
+}
+
+func (walker *YamlWalker) asTime(parts []string) (value time.Time, err error) {
+	w, err := walker.findNode(parts)
+	if err != nil {
+		return
+	}
+
+	s, ok := w.data.(time.Time)
+	if !ok {
+		err = ErrInvalidType
+		return
+	}
+	value = s
+	return
+}
+
 func (walker *YamlWalker) remove(parts []string, index int) error {
 	w, err := walker.findNode(parts)
 	if err != nil {
@@ -136,38 +190,282 @@ func (walker *YamlWalker) insert(parts []string, index int, node *YamlWalker) er
 	return nil
 }
 
-func (walker *YamlWalker) findNode(parts []string) (node *YamlWalker, err error) {
-	n := walker
+func (walker *YamlWalker) getComment(parts []string) (comment Comment, err error) {
+	node, err := walker.findNode(parts)
+	if err != nil {
+		return
+	}
+
+	comment = node.comment
 	if len(parts) == 0 {
-		node = n
 		return
 	}
 
-	m, ok := walker.data.(map[string]*YamlWalker)
-	if !ok {
+	key, err := walker.findKey(parts)
+	if err != nil || key == nil {
+		err = nil
+		return
+	}
+
+	comment.Head = key.comment.Head
+	comment.Foot = key.comment.Foot
+	if len(key.comment.Line) > 0 {
+		comment.Line = key.comment.Line
+	}
+
+	return
+}
+
+func (walker *YamlWalker) setComment(parts []string, comment Comment) (err error) {
+	node, err := walker.findNode(parts)
+	if err != nil {
+		return
+	}
+
+	var key *yamlKey
+	if len(parts) > 0 {
+		key, err = walker.findKey(parts)
+		if err != nil {
+			return
+		}
+	}
+	if key == nil {
+		node.comment = comment
+		return
+	}
+
+	// the line comment of a scalar is placed after the value, that of a collection after the key
+	key.comment = Comment{Head: comment.Head, Foot: comment.Foot}
+	node.comment.Head = ""
+	node.comment.Foot = ""
+	switch node.data.(type) {
+	case map[string]*YamlWalker, []*YamlWalker:
+		key.comment.Line = comment.Line
+		node.comment.Line = ""
+	default:
+		node.comment.Line = comment.Line
+	}
+
+	return
+}
+
+// findPosition returns the position of the node specified by path and of its key.
+// Unlike findNode() the last node is not resolved, so an alias has its own position.
+func (walker *YamlWalker) findPosition(parts []string) (node Position, key Position, err error) {
+	if len(parts) == 0 {
+		node = walker.position
+		return
+	}
+
+	parent, err := walker.findParent(parts)
+	if err != nil {
+		return
+	}
+
+	childName := parts[len(parts)-1]
+	switch x := parent.resolve().data.(type) {
+	case map[string]*YamlWalker:
+		keys, children := parent.merged(nil)
+		if childName == mergeKey {
+			keys, children = parent.resolve().keys, x
+		}
+		n, found := children[childName]
+		if !found {
+			err = ErrNotFound
+			return
+		}
+		node = n.position
+		for _, k := range keys {
+			if k.name == childName {
+				key = k.position
+				break
+			}
+		}
+	case []*YamlWalker:
+		index, e := sequenceIndex(childName, len(x))
+		if e != nil {
+			err = e
+			return
+		}
+		if index >= len(x) {
+			err = ErrInvalidRange
+			return
+		}
+		node = x[index].position
+	default:
 		err = ErrInvalidType
+	}
+
+	return
+}
+
+// findKey returns the key of the map item specified by path
+// or <nil> if the parent node is not a map.
+func (walker *YamlWalker) findKey(parts []string) (key *yamlKey, err error) {
+	parent, err := walker.findParent(parts)
+	if err != nil {
 		return
 	}
 
+	childName := parts[len(parts)-1]
+	for i := range parent.keys {
+		if parent.keys[i].name == childName {
+			key = &parent.keys[i]
+			return
+		}
+	}
+
+	return
+}
+
+func (walker *YamlWalker) findNode(parts []string) (node *YamlWalker, err error) {
+	n := walker
 	for i := 0; i < len(parts); i++ {
 		log(fmt.Sprintf("p:%v\n", parts[i]))
-		var ok bool
-		n, ok = m[parts[i]]
+		n, err = n.lookup(parts[i], nil)
+		if err != nil {
+			return
+		}
+	}
+
+	node = n
+
+	return
+}
+
+// child returns the child of the node specified by a single path element.
+// The element is a key name for maps and an index for sequences.
+// Negative indexes count from the end of sequence.
+func (walker *YamlWalker) child(part string) (node *YamlWalker, err error) {
+	switch x := walker.resolve().data.(type) {
+	case map[string]*YamlWalker:
+		n, ok := x[part]
 		if !ok {
 			err = ErrNotFound
 			return
 		}
-		if i < len(parts)-1 {
-			m, ok = n.data.(map[string]*YamlWalker)
-			if !ok {
-				err = ErrInvalidType
-				return
+		node = n.resolve()
+	case []*YamlWalker:
+		index, e := sequenceIndex(part, len(x))
+		if e != nil {
+			err = e
+			return
+		}
+		if index >= len(x) {
+			err = ErrInvalidRange
+			return
+		}
+		node = x[index].resolve()
+	default:
+		err = ErrInvalidType
+	}
+
+	return
+}
+
+// lookup returns the child of the node specified by a single path element the same way as child() does.
+// Keys missing in the map are looked up in the maps merged by the merge key "<<",
+// the keys of the map take priority over the merged ones, earlier merged maps over the later ones.
+// visited holds the maps already searched, it stops the recursive merges.
+func (walker *YamlWalker) lookup(part string, visited map[*YamlWalker]bool) (node *YamlWalker, err error) {
+	node, err = walker.child(part)
+	if !errors.Is(err, ErrNotFound) || part == mergeKey {
+		return
+	}
+
+	n := walker.resolve()
+	if visited == nil {
+		visited = make(map[*YamlWalker]bool)
+	}
+	visited[n] = true
+
+	for _, src := range n.mergeSources() {
+		if visited[src] {
+			continue
+		}
+		if child, e := src.lookup(part, visited); e == nil {
+			return child, nil
+		}
+	}
+	return
+}
+
+// merged returns the keys and the children of the map with the maps merged by the merge key "<<"
+// added the way lookup() finds them: the keys of the map come first followed by the merged keys
+// the map does not have. The merge key itself is left out.
+// If the node is not a map or has no merge key its own keys and children are returned.
+// visited holds the maps already merged, it stops the recursive merges.
+func (walker *YamlWalker) merged(visited map[*YamlWalker]bool) (keys []yamlKey, children map[string]*YamlWalker) {
+	n := walker.resolve()
+	x, _ := n.data.(map[string]*YamlWalker)
+	if _, found := x[mergeKey]; !found {
+		return n.keys, x
+	}
+	if visited == nil {
+		visited = make(map[*YamlWalker]bool)
+	}
+	visited[n] = true
+
+	keys = make([]yamlKey, 0, len(n.keys))
+	children = make(map[string]*YamlWalker, len(x))
+	for _, k := range n.keys {
+		if k.name != mergeKey {
+			keys = append(keys, k)
+			children[k.name] = x[k.name]
+		}
+	}
+	for _, src := range n.mergeSources() {
+		if visited[src] {
+			continue
+		}
+		srcKeys, srcChildren := src.merged(visited)
+		for _, k := range srcKeys {
+			if _, found := children[k.name]; !found {
+				keys = append(keys, k)
+				children[k.name] = srcChildren[k.name]
 			}
 		}
 	}
+	return
+}
+
+// mergeSources returns the maps merged into the map by the merge key "<<" in order of priority.
+// Merged nodes other than maps are skipped.
+func (walker *YamlWalker) mergeSources() []*YamlWalker {
+	merge, found := walker.resolve().data.(map[string]*YamlWalker)[mergeKey]
+	if !found {
+		return nil
+	}
 
-	node = n
+	merge = merge.resolve()
+	items := []*YamlWalker{merge}
+	if s, ok := merge.data.([]*YamlWalker); ok {
+		items = s
+	}
+	sources := make([]*YamlWalker, 0, len(items))
+	for _, item := range items {
+		item = item.resolve()
+		if _, ok := item.data.(map[string]*YamlWalker); ok {
+			sources = append(sources, item)
+		}
+	}
+	return sources
+}
 
+// sequenceIndex converts the path element to an index of a sequence of length size.
+// Negative index counts from the end.
+func sequenceIndex(part string, size int) (index int, err error) {
+	index, e := strconv.Atoi(part)
+	if e != nil {
+		err = ErrInvalidType
+		return
+	}
+	if index < 0 {
+		index += size
+	}
+	if index < 0 {
+		err = ErrInvalidRange
+	}
 	return
 }
 
@@ -213,6 +511,14 @@ func (walker *YamlWalker) appendNode(parts []string, node *YamlWalker, keyStyle
 
 	childName := parts[len(parts)-1]
 
+	if s, ok := parent.data.([]*YamlWalker); ok {
+		index, e := sequenceIndex(childName, len(s))
+		if e != nil {
+			return e
+		}
+		return parent.insert([]string{}, index, node)
+	}
+
 	if parent.keyExists(childName) {
 		return ErrDuplicateKey
 	}
@@ -235,6 +541,65 @@ func (walker *YamlWalker) appendNode(parts []string, node *YamlWalker, keyStyle
 	return
 }
 
+func (walker *YamlWalker) setPath(parts []string, value interface{}, options *setPathOptions) error {
+	if len(parts) == 0 {
+		walker.Update(value)
+		return nil
+	}
+
+	n := walker.resolve()
+	for i, part := range parts {
+		last := i == len(parts)-1
+
+		child, err := n.child(part)
+		if err == nil {
+			if !last {
+				n = child
+				continue
+			}
+			child.Update(value)
+			return nil
+		}
+
+		var created *YamlWalker
+		switch {
+		case last:
+			created = NewYamlWalker()
+			created.Update(value)
+		case parts[i+1] == "0":
+			created = NewYamlWalker(options.childStyle)
+			created.Update(make([]*YamlWalker, 0))
+		default:
+			created = NewYamlWalker(options.childStyle)
+			created.Update(make(map[string]*YamlWalker))
+		}
+
+		switch {
+		case errors.Is(err, ErrNotFound):
+			err = n.appendNode([]string{part}, created, options.keyStyle)
+		case errors.Is(err, ErrInvalidRange):
+			if s := n.data.([]*YamlWalker); part == strconv.Itoa(len(s)) {
+				err = n.insert([]string{}, len(s), created)
+			}
+		case errors.Is(err, ErrInvalidType) && n.data == nil:
+			if part == "0" {
+				n.Update(make([]*YamlWalker, 0))
+				err = n.insert([]string{}, 0, created)
+			} else {
+				n.Update(make(map[string]*YamlWalker))
+				err = n.appendNode([]string{part}, created, options.keyStyle)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		n = created
+	}
+
+	return nil
+}
+
 func (walker *YamlWalker) deleteNode(parts []string) (err error) {
 	parent, err := walker.findParent(parts)
 	if err != nil {
@@ -242,6 +607,16 @@ func (walker *YamlWalker) deleteNode(parts []string) (err error) {
 	}
 	childName := parts[len(parts)-1]
 
+	if s, ok := parent.data.([]*YamlWalker); ok {
+		index, e := sequenceIndex(childName, len(s))
+		if e != nil {
+			err = e
+			return
+		}
+		err = parent.remove([]string{}, index)
+		return
+	}
+
 	m, ok := parent.data.(map[string]*YamlWalker)
 	if !ok {
 		err = ErrInvalidType
@@ -266,6 +641,248 @@ func (walker *YamlWalker) deleteNode(parts []string) (err error) {
 	return
 }
 
+// mapParent returns the map holding the item specified by path and the index of its key
+// or -1 if the key does not exist
+func (walker *YamlWalker) mapParent(parts []string) (parent *YamlWalker, index int, err error) {
+	if len(parts) == 0 {
+		err = ErrKeyMismatch
+		return
+	}
+
+	parent, err = walker.findParent(parts)
+	if err != nil {
+		return
+	}
+	parent = parent.resolve()
+
+	if _, ok := parent.data.(map[string]*YamlWalker); !ok {
+		err = ErrInvalidType
+		return
+	}
+	index = parent.keyIndex(parts[len(parts)-1])
+
+	return
+}
+
+func (walker *YamlWalker) renameKey(parts []string, newName string) error {
+	parent, index, err := walker.mapParent(parts)
+	if err != nil {
+		return err
+	}
+	if index < 0 {
+		return ErrNotFound
+	}
+
+	oldName := parts[len(parts)-1]
+	if oldName == newName {
+		return nil
+	}
+	if parent.keyExists(newName) {
+		return ErrDuplicateKey
+	}
+
+	m := parent.data.(map[string]*YamlWalker)
+	m[newName] = m[oldName]
+	delete(m, oldName)
+	parent.keys[index].name = newName
+	parent.keys[index].node = nil
+	// the repeated keys keep repeating the key
+	duplicates := make([]duplicate, len(parent.keys[index].duplicates))
+	for i, d := range parent.keys[index].duplicates {
+		d.key.name = newName
+		d.key.node = nil
+		duplicates[i] = d
+	}
+	parent.keys[index].duplicates = duplicates
+
+	return nil
+}
+
+func (walker *YamlWalker) moveKey(parts []string, placement KeyPlacement, sibling string) error {
+	parent, index, err := walker.mapParent(parts)
+	if err != nil {
+		return err
+	}
+	if index < 0 || !parent.keyExists(sibling) {
+		return ErrNotFound
+	}
+
+	key := parent.keys[index]
+	parent.keys = append(parent.keys[:index], parent.keys[index+1:]...)
+
+	target := parent.keyIndex(sibling)
+	if target < 0 {
+		// the key is moved relative to itself
+		target = index
+	} else if placement == KeyAfter {
+		target++
+	}
+	parent.keys = append(parent.keys[:target], append([]yamlKey{key}, parent.keys[target:]...)...)
+
+	return nil
+}
+
+func (walker *YamlWalker) insertKeyAt(parts []string, index int, node *YamlWalker, keyStyle yaml.Style) error {
+	parent, existing, err := walker.mapParent(parts)
+	if err != nil {
+		return err
+	}
+	if existing >= 0 {
+		return ErrDuplicateKey
+	}
+	if index < 0 || index > len(parent.keys) {
+		return ErrInvalidRange
+	}
+
+	name := parts[len(parts)-1]
+	parent.data.(map[string]*YamlWalker)[name] = node
+	key := yamlKey{name: name, style: keyStyle}
+	parent.keys = append(parent.keys[:index], append([]yamlKey{key}, parent.keys[index:]...)...)
+
+	return nil
+}
+
+func (walker *YamlWalker) sortKeys(recursive bool, less func(a, b string) bool) {
+	if walker.alias != nil {
+		return
+	}
+
+	switch x := walker.data.(type) {
+	case map[string]*YamlWalker:
+		sort.SliceStable(walker.keys, func(i, j int) bool {
+			return less(walker.keys[i].name, walker.keys[j].name)
+		})
+		walker.placeDuplicates()
+		if recursive {
+			for _, v := range x {
+				v.sortKeys(recursive, less)
+			}
+		}
+	case []*YamlWalker:
+		if recursive {
+			for _, v := range x {
+				v.sortKeys(recursive, less)
+			}
+		}
+	}
+}
+
+// placeDuplicates places the repeated keys right after the first key of the same name
+func (walker *YamlWalker) placeDuplicates() {
+	index := 0
+	for i, k := range walker.keys {
+		index++
+		if len(k.duplicates) == 0 {
+			continue
+		}
+		duplicates := make([]duplicate, len(k.duplicates))
+		for j, d := range k.duplicates {
+			d.index = index
+			duplicates[j] = d
+			index++
+		}
+		walker.keys[i].duplicates = duplicates
+	}
+}
+
+// keyIndex returns the index of the key or -1 if the key does not exist
+func (walker *YamlWalker) keyIndex(keyName string) int {
+	return keyIndex(walker.keys, keyName)
+}
+
+func keyIndex(keys []yamlKey, keyName string) int {
+	for i, k := range keys {
+		if k.name == keyName {
+			return i
+		}
+	}
+	return -1
+}
+
+// keyIndexOf returns the index of the key equal to the typed value or -1 if there is no such key.
+// The value *YamlWalker is compared with the key node, including map and sequence keys.
+func (walker *YamlWalker) keyIndexOf(value interface{}) int {
+	for i, k := range walker.keys {
+		if node, ok := value.(*YamlWalker); ok {
+			if k.asNode().equal(node, sameValue) {
+				return i
+			}
+			continue
+		}
+		if k.node == nil {
+			if name, ok := value.(string); ok && name == k.name {
+				return i
+			}
+			continue
+		}
+		if compareValues(k.node.resolve().data, value) == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// asNode returns the key as a node, string keys are turned into new scalar nodes
+func (key yamlKey) asNode() *YamlWalker {
+	if key.node != nil {
+		return key.node
+	}
+	n := NewYamlWalker(key.style)
+	n.data = key.name
+	n.tag = "!!str"
+	return n
+}
+
+// tag returns the tag of the key, "!!str" for string keys
+func (key yamlKey) tag() string {
+	if key.node == nil {
+		return "!!str"
+	}
+	n := key.node.resolve()
+	switch n.data.(type) {
+	case map[string]*YamlWalker:
+		return "!!map"
+	case []*YamlWalker:
+		return "!!seq"
+	}
+	return n.tag
+}
+
+// resolvedTag returns the tag the node is written with, the empty tag is derived from the value
+func (walker *YamlWalker) resolvedTag() string {
+	switch walker.data.(type) {
+	case map[string]*YamlWalker:
+		return "!!map"
+	case []*YamlWalker:
+		return "!!seq"
+	}
+	if len(walker.tag) > 0 {
+		return walker.tag
+	}
+	if walker.data == nil {
+		return "!!null"
+	}
+	_, tag := encodeValue(walker.data)
+	return tag
+}
+
+// qualifiedName returns the name prefixed with the tag,
+// it is the name of the key written the same way as a key of another type, e.g. "!!int 1"
+func (key yamlKey) qualifiedName() string {
+	return key.tag() + " " + key.name
+}
+
+// appendEntry appends the node to the map with the key taken as is, i.e. with its type, style and comments
+func (walker *YamlWalker) appendEntry(key yamlKey, node *YamlWalker) error {
+	if err := walker.appendNode([]string{key.name}, node, key.style); err != nil {
+		return err
+	}
+	last := &walker.keys[len(walker.keys)-1]
+	last.node = key.node
+	last.comment = key.comment
+	return nil
+}
+
 func (walker *YamlWalker) keyExists(keyName string) bool {
 	for _, k := range walker.keys {
 		if k.name == keyName {
@@ -274,3 +891,165 @@ func (walker *YamlWalker) keyExists(keyName string) bool {
 	}
 	return false
 }
+
+// resolve follows the chain of aliases and returns the anchored node
+func (walker *YamlWalker) resolve() *YamlWalker {
+	n := walker
+	for n.alias != nil {
+		n = n.alias
+	}
+	return n
+}
+
+// deepCopy returns independent copy of the node and all its children.
+// Aliases referring to nodes inside the subtree refer to the copies,
+// aliases referring to nodes outside the subtree are replaced by copies of the anchored nodes.
+func (walker *YamlWalker) deepCopy() *YamlWalker {
+	copies := make(map[*YamlWalker]*YamlWalker)
+	n := walker.copyNode(copies)
+	n.fixAliases(copies)
+	return n
+}
+
+func (walker *YamlWalker) copyNode(copies map[*YamlWalker]*YamlWalker) *YamlWalker {
+	n := &YamlWalker{
+		keys:     make([]yamlKey, len(walker.keys)),
+		style:    walker.style,
+		tag:      walker.tag,
+		raw:      walker.raw,
+		anchor:   walker.anchor,
+		alias:    walker.alias,
+		comment:  walker.comment,
+		position: walker.position,
+	}
+	copy(n.keys, walker.keys)
+	for i, k := range n.keys {
+		if k.node != nil {
+			n.keys[i].node = k.node.deepCopy()
+		}
+		if len(k.duplicates) == 0 {
+			continue
+		}
+		n.keys[i].duplicates = make([]duplicate, len(k.duplicates))
+		for j, d := range k.duplicates {
+			n.keys[i].duplicates[j] = duplicate{key: d.key, node: d.node.copyNode(copies), index: d.index}
+		}
+	}
+	copies[walker] = n
+
+	switch x := walker.data.(type) {
+	case map[string]*YamlWalker:
+		m := make(map[string]*YamlWalker, len(x))
+		for k, v := range x {
+			m[k] = v.copyNode(copies)
+		}
+		n.data = m
+	case []*YamlWalker:
+		s := make([]*YamlWalker, len(x))
+		for i, v := range x {
+			s[i] = v.copyNode(copies)
+		}
+		n.data = s
+	default:
+		n.data = x
+	}
+
+	return n
+}
+
+func (walker *YamlWalker) fixAliases(copies map[*YamlWalker]*YamlWalker) {
+	if walker.alias != nil {
+		if target, found := copies[walker.alias]; found {
+			walker.alias = target
+		} else {
+			*walker = *walker.expandAlias()
+		}
+		return
+	}
+
+	switch x := walker.data.(type) {
+	case map[string]*YamlWalker:
+		for _, v := range x {
+			v.fixAliases(copies)
+		}
+	case []*YamlWalker:
+		for _, v := range x {
+			v.fixAliases(copies)
+		}
+	}
+}
+
+// expandAlias returns the copy of the anchored node to be placed instead of the alias
+func (walker *YamlWalker) expandAlias() *YamlWalker {
+	n := walker.resolve().deepCopy()
+	n.anchor = ""
+	n.comment = walker.comment
+	return n
+}
+
+// replaceWith takes the value and the style of src keeping comments and anchor
+func (walker *YamlWalker) replaceWith(src *YamlWalker) {
+	walker.data = src.data
+	walker.keys = src.keys
+	walker.style = src.style
+	walker.tag = src.tag
+	walker.raw = src.raw
+	walker.alias = nil
+}
+
+func (walker *YamlWalker) isScalar() bool {
+	if walker.alias != nil {
+		return false
+	}
+	switch walker.data.(type) {
+	case map[string]*YamlWalker, []*YamlWalker:
+		return false
+	}
+	return true
+}
+
+func (walker *YamlWalker) isMap() bool {
+	_, ok := walker.data.(map[string]*YamlWalker)
+	return ok
+}
+
+func (walker *YamlWalker) isNull() bool {
+	return walker.data == nil && walker.alias == nil
+}
+
+// equal reports whether the nodes hold the same values regardless of styles, comments and order of keys.
+// Scalars are compared with same, e.g. sameValue.
+func (walker *YamlWalker) equal(other *YamlWalker, same func(a, b interface{}) bool) bool {
+	a, b := walker.resolve(), other.resolve()
+	switch x := a.data.(type) {
+	case map[string]*YamlWalker:
+		y, ok := b.data.(map[string]*YamlWalker)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, found := y[k]
+			if !found || !v.equal(w, same) {
+				return false
+			}
+		}
+		return true
+	case []*YamlWalker:
+		y, ok := b.data.([]*YamlWalker)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !x[i].equal(y[i], same) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch b.data.(type) {
+	case map[string]*YamlWalker, []*YamlWalker:
+		return false
+	}
+	return same(a.data, b.data)
+}
@@ -2,6 +2,8 @@ package yamlwalker
 
 import (
 	"errors"
+	"sort"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,17 +14,101 @@ var (
 	ErrKeyMismatch  = errors.New("list of keys does not match map keys")
 	ErrDuplicateKey = errors.New("duplicate key name")
 	ErrInvalidRange = errors.New("index out of bounds")
+	ErrInvalidAlias = errors.New("alias target has no anchor")
 )
 
 type YamlWalker struct {
-	data  interface{}
-	keys  []yamlKey
-	style yaml.Style
+	data     interface{}
+	keys     []yamlKey
+	style    yaml.Style
+	tag      string
+	raw      *string // source text of a decoded scalar, <nil> once the value is updated
+	anchor   string
+	alias    *YamlWalker
+	comment  Comment
+	position Position
+	policy   DuplicateKeyPolicy // applied by UnmarshalYAML()
 }
 
 type yamlKey struct {
-	style yaml.Style
-	name  string
+	style      yaml.Style
+	name       string      // name used by paths and as the index of the map
+	node       *YamlWalker // decoded key which is not a plain string, e.g. 1, true or [a, b]; <nil> for string keys
+	comment    Comment
+	position   Position
+	duplicates []duplicate // repeated keys of the same name, see DuplicateKeyKeepAll
+}
+
+// duplicate is a repeated key of a map together with its value
+type duplicate struct {
+	key   yamlKey
+	node  *YamlWalker
+	index int // index of the key among all keys of the map in the source, including repeated ones
+}
+
+// DuplicateKeyPolicy defines how repeated keys of a map are decoded
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeyError fails decoding with ErrDuplicateKey reporting lines of both keys
+	DuplicateKeyError DuplicateKeyPolicy = iota
+	// DuplicateKeyFirstWins keeps the first key and drops the repeated ones
+	DuplicateKeyFirstWins
+	// DuplicateKeyLastWins keeps the last key at its position and drops the previous ones
+	DuplicateKeyLastWins
+	// DuplicateKeyKeepAll keeps all keys to be written back as they are.
+	// The value of the first key is the value of the map item, the repeated keys are not visible to the API.
+	// They are deleted and renamed together with the first key of the same name and keep their index
+	// among the keys of the map, the index beyond the last key places them at the end.
+	// SortKeys() places them right after the first key.
+	DuplicateKeyKeepAll
+)
+
+// Comment holds the comments attached to a node.
+// Every non empty comment must include the leading '#'.
+type Comment struct {
+	Head string // comment in the lines preceding the node
+	Line string // comment at the end of the line where the node is
+	Foot string // comment in the lines following the node
+}
+
+// Position is the location of a node in the source, line and column start at 1.
+// Zero position means the node was not decoded, e.g. it was created by Append().
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Kind is the kind of the node returned by Kind()
+type Kind int
+
+const (
+	// KindNull is a scalar holding null, e.g. null, ~ or the empty value
+	KindNull Kind = iota
+	// KindScalar is a scalar other than null
+	KindScalar
+	// KindMapping is a map
+	KindMapping
+	// KindSequence is a sequence
+	KindSequence
+	// KindAlias is an alias of the anchored node
+	KindAlias
+)
+
+func (kind Kind) String() string {
+	switch kind {
+	case KindNull:
+		return "null"
+	case KindScalar:
+		return "scalar"
+	case KindMapping:
+		return "mapping"
+	case KindSequence:
+		return "sequence"
+	case KindAlias:
+		return "alias"
+	}
+	return "unknown"
 }
 
 const (
@@ -30,6 +116,9 @@ const (
 	Separator string = "."
 )
 
+// mergeKey is the key merging the maps of its value into the map, e.g. "<<: *defaults"
+const mergeKey = "<<"
+
 // NewYamlWalker creates new YamlWalker node instance with a specified dataStyle
 // Default dataStyle = 0.
 func NewYamlWalker(dataStyle ...yaml.Style) *YamlWalker {
@@ -43,26 +132,85 @@ func NewYamlWalker(dataStyle ...yaml.Style) *YamlWalker {
 	}
 }
 
+// NewMapping creates new empty map node
+func NewMapping() *YamlWalker {
+	n := NewYamlWalker()
+	n.data = make(map[string]*YamlWalker)
+	return n
+}
+
+// NewSequence creates new sequence node holding the copies of items, see Update()
+func NewSequence(items ...*YamlWalker) *YamlWalker {
+	n := NewYamlWalker()
+	n.Update(items)
+	return n
+}
+
+// NewScalar creates new scalar node holding the value written in the style,
+// e.g. NewScalar("yes", yaml.DoubleQuotedStyle).
+// The value is set by Update(), <nil> creates the null node.
+func NewScalar(value interface{}, style yaml.Style) *YamlWalker {
+	n := NewYamlWalker(style)
+	n.Update(value)
+	return n
+}
+
+// NewNull creates new null node, it is written as null
+func NewNull() *YamlWalker {
+	return NewYamlWalker()
+}
+
+// Kind returns the kind of the node.
+// The alias is KindAlias, use Get() or Value() to reach the anchored node.
+func (walker *YamlWalker) Kind() Kind {
+	if walker.alias != nil {
+		return KindAlias
+	}
+	switch walker.data.(type) {
+	case map[string]*YamlWalker:
+		return KindMapping
+	case []*YamlWalker:
+		return KindSequence
+	case nil:
+		return KindNull
+	}
+	return KindScalar
+}
+
 // UnmarshalYAML decode YAML into internal representation
 func (walker *YamlWalker) UnmarshalYAML(value *yaml.Node) error {
 
-	newYW, err := walker.decode(value)
+	state := newDecodeState()
+	state.duplicates = walker.policy
+	newYW, err := walker.decode(value, state)
 	if err != nil {
 		return err
 	}
 
 	walker.data = newYW.data
 	walker.keys = newYW.keys
+	walker.tag = newYW.tag
+	walker.raw = newYW.raw
+	walker.anchor = newYW.anchor
+	walker.alias = newYW.alias
+	walker.comment = newYW.comment
+	walker.position = newYW.position
 
 	return nil
 }
 
 // MarshalYAML encode internal representation to YAML
 func (walker *YamlWalker) MarshalYAML() (interface{}, error) {
-	buffer, err := walker.encode()
+	buffer, err := walker.encodeDocument()
 	return buffer, err
 }
 
+// SetDuplicateKeyPolicy sets the policy for repeated keys of maps applied when the node is unmarshaled.
+// Default policy is DuplicateKeyError.
+func (walker *YamlWalker) SetDuplicateKeyPolicy(policy DuplicateKeyPolicy) {
+	walker.policy = policy
+}
+
 // Style returns current node style
 func (walker *YamlWalker) Style() yaml.Style {
 	return walker.style
@@ -73,15 +221,162 @@ func (walker *YamlWalker) SetStyle(style yaml.Style) {
 	walker.style = style
 }
 
+// Tag returns current node tag, e.g. "!!int" or custom "!Ref".
+// Empty tag means the tag is derived from the value when node is encoded.
+func (walker *YamlWalker) Tag() string {
+	return walker.tag
+}
+
+// SetTag sets current node tag
+func (walker *YamlWalker) SetTag(tag string) {
+	walker.tag = tag
+}
+
+// Anchor returns the anchor name of the node or empty string if the node has no anchor
+func (walker *YamlWalker) Anchor() string {
+	return walker.anchor
+}
+
+// SetAnchor sets the anchor name of the node.
+// Empty name removes the anchor.
+func (walker *YamlWalker) SetAnchor(name string) {
+	walker.anchor = name
+}
+
+// Alias returns the node the current node refers to or <nil> if the node is not an alias
+func (walker *YamlWalker) Alias() *YamlWalker {
+	return walker.alias
+}
+
+// SetAlias turns the node into an alias of target.
+// All previouse data is lost.
+// The target must have an anchor by the time the tree is encoded otherwise encoding fails with ErrInvalidAlias.
+func (walker *YamlWalker) SetAlias(target *YamlWalker) {
+	walker.data = nil
+	walker.keys = make([]yamlKey, 0)
+	walker.alias = target
+}
+
+// Comment returns comments of the node specified by path.
+// If the node is an item of a map the comments attached to the key are returned,
+// the line comment of scalar values is returned if the key has no line comment.
+// If path does not exists err set to ErrNotFound.
+func (walker *YamlWalker) Comment(path string) (comment Comment, err error) {
+	parts, err := ParsePath(path)
+	if err != nil {
+		return
+	}
+	return walker.getComment(parts)
+}
+
+// SetComment sets comments of the node specified by path.
+// Empty strings remove comments.
+// If the node is an item of a map the comments are attached to the key.
+// If path does not exists err set to ErrNotFound.
+func (walker *YamlWalker) SetComment(path string, comment Comment) error {
+	parts, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	return walker.setComment(parts, comment)
+}
+
+// Position returns the position of the node specified by path in the source.
+// The position of an alias is that of the alias itself, not of the anchored node.
+// Keys merged by the merge key "<<" have the position in the merged map.
+// If path does not exists err set to ErrNotFound.
+func (walker *YamlWalker) Position(path string) (Position, error) {
+	parts, err := ParsePath(path)
+	if err != nil {
+		return Position{}, err
+	}
+	node, _, err := walker.findPosition(parts)
+	return node, err
+}
+
+// KeyPosition returns the position of the key of the map item specified by path in the source.
+// Zero position is returned for the root node and items of sequences.
+// If path does not exists err set to ErrNotFound.
+func (walker *YamlWalker) KeyPosition(path string) (Position, error) {
+	parts, err := ParsePath(path)
+	if err != nil {
+		return Position{}, err
+	}
+	_, key, err := walker.findPosition(parts)
+	return key, err
+}
+
 // AsMap returns children of the node specified by path
 // as map if node is yaml.MappingNode and err set to nil.
 // If path does not exists err set to ErrNotFound.
 // If the node is not yaml.MappingNode err set to ErrInvalidType.
 //
-// The map is usefull to iterate over the node children.
+// The map is usefull to look up the node children, it has no order of keys.
+// Use Keys(), Entries() or Range() to iterate over the children in document order.
 // Do not insert, delete or change elements of map directly, use Append(), Delete() or Update() instead.
 func (walker *YamlWalker) AsMap(path string) (children map[string]*YamlWalker, err error) {
-	return walker.asMap(walker.splitPath(path))
+	parts, err := ParsePath(path)
+	if err != nil {
+		return
+	}
+	return walker.asMap(parts)
+}
+
+// Entry is a child of a map
+type Entry struct {
+	Key      string
+	KeyStyle yaml.Style
+	KeyNode  *YamlWalker // copy of the key for keys other than strings, <nil> for string keys
+	Node     *YamlWalker // aliases are resolved to the anchored nodes
+}
+
+// Keys returns key names of the map specified by path in document order.
+// The returned slice is a copy, changing it does not affect the map.
+// If path does not exists err set to ErrNotFound.
+// If the node is not yaml.MappingNode err set to ErrInvalidType.
+func (walker *YamlWalker) Keys(path string) ([]string, error) {
+	entries, err := walker.Entries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	return keys, nil
+}
+
+// Entries returns children of the map specified by path in document order.
+// The returned slice is a copy, changing it does not affect the map.
+// If path does not exists err set to ErrNotFound.
+// If the node is not yaml.MappingNode err set to ErrInvalidType.
+func (walker *YamlWalker) Entries(path string) ([]Entry, error) {
+	parts, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return walker.entries(parts)
+}
+
+// Range calls fn for every child of the map specified by path in document order
+// until fn returns false.
+// The children are taken before the first call, so fn may add or delete keys of the map safely,
+// the added keys are not visited and the deleted ones are still visited.
+// If path does not exists err set to ErrNotFound.
+// If the node is not yaml.MappingNode err set to ErrInvalidType.
+func (walker *YamlWalker) Range(path string, fn func(key string, node *YamlWalker) bool) error {
+	entries, err := walker.Entries(path)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if !fn(e.Key, e.Node) {
+			break
+		}
+	}
+	return nil
 }
 
 // AsSlice returns children of the node specified by path
@@ -91,28 +386,66 @@ func (walker *YamlWalker) AsMap(path string) (children map[string]*YamlWalker, e
 // The slice is usefull to iterate over the node children.
 // Do not insert, delete or change elements of slice directly, use Insert(), Remove() or Update() instead.
 func (walker *YamlWalker) AsSlice(path string) (children []*YamlWalker, err error) {
-	return walker.asSlice(walker.splitPath(path))
+	parts, err := ParsePath(path)
+	if err != nil {
+		return
+	}
+	return walker.asSlice(parts)
 }
 
 // AsString returns tyhe value of the node specified by path as string.
 // If the node value is string it is returned and err set to nil.
 // If the node is not a string err set to ErrInvalidType.
 func (walker *YamlWalker) AsString(path string) (string, error) {
-	return walker.asString(walker.splitPath(path))
+	parts, err := ParsePath(path)
+	if err != nil {
+		return "", err
+	}
+	return walker.asString(parts)
 }
 
 // AsInt returns the value of the node specified by path as int.
 // If the node value is int it is returned and err set to nil.
 // If the node is not an int err set to ErrInvalidType.
 func (walker *YamlWalker) AsInt(path string) (int, error) {
-	return walker.asInt(walker.splitPath(path))
+	parts, err := ParsePath(path)
+	if err != nil {
+		return 0, err
+	}
+	return walker.asInt(parts)
 }
 
 // AsBool returns the value of the node specified by path as bool.
 // If the node value is bool it is returned and err set to nil.
 // If the node is not a bool err set to ErrInvalidType.
 func (walker *YamlWalker) AsBool(path string) (bool, error) {
-	return walker.asBool(walker.splitPath(path))
+	parts, err := ParsePath(path)
+	if err != nil {
+		return false, err
+	}
+	return walker.asBool(parts)
+}
+
+// AsFloat returns the value of the node specified by path as float64.
+// If the node value is float64 it is returned and err set to nil.
+// If the node is not a float64 err set to ErrInvalidType.
+func (walker *YamlWalker) AsFloat(path string) (float64, error) {
+	parts, err := ParsePath(path)
+	if err != nil {
+		return 0, err
+	}
+	return walker.asFloat(parts)
+}
+
+// AsTime returns the value of the node specified by path as time.Time.
+// If the node value is !!timestamp it is returned and err set to nil.
+// If the node is not a time.Time err set to ErrInvalidType.
+func (walker *YamlWalker) AsTime(path string) (time.Time, error) {
+	parts, err := ParsePath(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return walker.asTime(parts)
 }
 
 // Remove removes the item at the index from the slice of children.
@@ -120,7 +453,11 @@ func (walker *YamlWalker) AsBool(path string) (bool, error) {
 // and err set to nil otherwise err set to ErrInvalidType.
 // If index is out of slice bounds err set to ErrInvalidRange.
 func (walker *YamlWalker) Remove(path string, index int) error {
-	return walker.remove(walker.splitPath(path), index)
+	parts, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	return walker.remove(parts, index)
 }
 
 // Insert inserts the node into the slice of children at the index.
@@ -129,20 +466,80 @@ func (walker *YamlWalker) Remove(path string, index int) error {
 // If the index == len(children) the node is appnded at the end of slice.
 // If index is out of slice bounds err set to ErrInvalidRange.
 func (walker *YamlWalker) Insert(path string, index int, node *YamlWalker) error {
-	return walker.insert(walker.splitPath(path), index, node)
+	parts, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	return walker.insert(parts, index, node)
 }
 
-// Value returns the value of the node
+// Value returns the value of the node.
+// If the node is an alias the value of the anchored node is returned.
 func (walker *YamlWalker) Value() interface{} {
-	return walker.data
+	return walker.resolve().data
 }
 
 // Update updates the value of the node.
-// All previouse data is lost.
-// To assign mapped tree of new nodes or sequence of nodes use Set() instead.
+// All previouse data and the tag are lost, comments and anchor are kept.
+// The style is kept unless the type of the value changes, e.g. "8080" updated to 9090 is written plain.
+// If value is *YamlWalker the node takes the copy of its value, keys, style and tag, see Clone().
+// If value is map[string]*YamlWalker or []*YamlWalker the children are copied,
+// keys of the map are sorted as the map has no order.
+// Updating the null node with <nil> keeps the way it is written, e.g. ~.
 func (walker *YamlWalker) Update(value interface{}) {
-	walker.data = value
-	walker.keys = make([]yamlKey, 0)
+	if value == nil && walker.isNull() {
+		return
+	}
+
+	// the value is copied before the node is reset as it may be the node itself or its ancestor
+	keys := make([]yamlKey, 0)
+	var data interface{}
+	switch x := value.(type) {
+	case *YamlWalker:
+		walker.replaceWith(x.Clone())
+		return
+	case map[string]*YamlWalker:
+		names := make([]string, 0, len(x))
+		for name := range x {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		m := make(map[string]*YamlWalker, len(x))
+		for _, name := range names {
+			m[name] = x[name].Clone()
+			keys = append(keys, yamlKey{name: name})
+		}
+		data = m
+	case []*YamlWalker:
+		s := make([]*YamlWalker, len(x))
+		for i, v := range x {
+			s[i] = v.Clone()
+		}
+		data = s
+	default:
+		data = value
+	}
+
+	// the style of another type does not fit the value, e.g. quotes of a string updated to a number;
+	// the null node keeps the style it was created with, see NewScalar()
+	updated := &YamlWalker{data: data}
+	if !walker.isNull() && walker.resolvedTag() != updated.resolvedTag() {
+		walker.style = 0
+	}
+
+	walker.keys = keys
+	walker.alias = nil
+	walker.tag = ""
+	walker.raw = nil
+	walker.data = data
+}
+
+// Clone returns independent deep copy of the node with keys, styles, tags, comments and positions.
+// Aliases referring to nodes inside the subtree refer to the copies,
+// aliases referring to nodes outside the subtree are replaced by copies of the anchored nodes.
+func (walker *YamlWalker) Clone() *YamlWalker {
+	return walker.deepCopy()
 }
 
 // GetValue returns the value of the node specified by path or <nil> if node does not exists
@@ -160,20 +557,63 @@ func (walker *YamlWalker) GetValue(path string) interface{} {
 }
 
 // Get returns the node specified by path or ErrNotFound if node does not exists
-// It searches through the tree of mapping nodes (Kind == yaml.MappingNode).
+// It searches through the tree of mapping nodes (Kind == yaml.MappingNode) by key names
+// and sequence nodes (Kind == yaml.SequenceNode) by indexes, e.g. "servers.0.url" or "servers[0].url".
+// Negative index counts from the end of sequence, i.e. "servers.-1" is the last item.
+// Aliases met on the way are resolved to the anchored nodes.
+// Keys missing in a map are looked up in the maps merged by the merge key "<<",
+// e.g. "svc.image" finds the image of "svc: {<<: *base}"; the keys of the map take priority.
+// The node found through the merge key belongs to the merged map, changing it changes
+// all maps merging it the same way as changing the node through an alias does.
 // Empty path returns the top node.
-// If node Kind other than yaml.MappingNode occurs in the middle of the tree it returns ErrInvalidType.
+// If node Kind other than yaml.MappingNode or yaml.SequenceNode occurs in the middle of the tree
+// or the sequence is indexed by a name it returns ErrInvalidType.
+// If the index is out of sequence bounds it returns ErrInvalidRange.
+// Key names containing separators are quoted or escaped, e.g. `labels."app.kubernetes.io/name"`,
+// see ParsePath() for the syntax. If path can not be parsed it returns ErrInvalidPath.
 func (walker *YamlWalker) Get(path string) (node *YamlWalker, err error) {
 	if len(path) == 0 {
 		node = walker
 		return
 	}
 
-	node, err = walker.findNode(walker.splitPath(path))
+	parts, err := ParsePath(path)
+	if err != nil {
+		return
+	}
+	node, err = walker.findNode(parts)
 
 	return
 }
 
+// GetByKey returns the child of the map specified by path having the key of the typed value,
+// e.g. GetByKey("ports", 80) finds the key written as 80 but not "80".
+// Numbers are compared by value, *YamlWalker is compared with the key node,
+// which makes map and sequence keys reachable.
+// If path or the key does not exists it returns ErrNotFound.
+// If the node is not a map it returns ErrInvalidType.
+//
+// Get() finds such keys by the name written in the source, e.g. `ports.80` or `"[a, b]"`.
+// When the same name is used by keys of different types, the string key keeps the name
+// and the name of the other key is prefixed with its tag, e.g. `"!!int 80"`.
+func (walker *YamlWalker) GetByKey(path string, key interface{}) (*YamlWalker, error) {
+	node, err := walker.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	node = node.resolve()
+
+	m, ok := node.data.(map[string]*YamlWalker)
+	if !ok {
+		return nil, ErrInvalidType
+	}
+	i := node.keyIndexOf(key)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	return m[node.keys[i].name].resolve(), nil
+}
+
 // SetValue sets the value of the node at the specified path.
 // All previouse data is lost.
 // If path does not exists it silently does nothing.
@@ -185,26 +625,152 @@ func (walker *YamlWalker) SetValue(path string, value interface{}) {
 	node.Update(value)
 }
 
-// Set sets the node at the specified path by making a copy of node properties.
-// All previouse data is lost.
+// Set sets the node at the specified path to the deep copy of node,
+// i.e. its value, keys, styles and tags. The trees do not share nodes afterwards,
+// so the node may be taken from another document.
+// All previouse data is lost, comments and anchor of the existing node are kept.
 // It returns ErrNotFound if node does not exists.
-// It searches through the tree of mapping nodes (Kind == yaml.MappingNode).
-// Empty calling with empty path is equivalent to call SetValue(node.Value()).
-// If node Kind other than yaml.MappingNode occurs in the middle of the tree it returns ErrInvalidType.
+// It searches through the tree the same way as Get() does.
+// Calling with empty path is equivalent to call Update(node).
 func (walker *YamlWalker) Set(path string, node *YamlWalker) error {
 	existing, err := walker.Get(path)
 	if err != nil {
 		return ErrNotFound
 	}
 
-	existing.Update(node.Value())
+	existing.Update(node)
 	return nil
 }
 
+// KeyPlacement defines where MoveKey() places the key relative to the sibling
+type KeyPlacement int
+
+const (
+	KeyBefore KeyPlacement = iota
+	KeyAfter
+)
+
+// RenameKey renames the key of the map item specified by path keeping its position, style and comments.
+// If path does not exists it returns ErrNotFound.
+// If the parent is not a map it returns ErrInvalidType.
+// If newName already exists it returns ErrDuplicateKey.
+func (walker *YamlWalker) RenameKey(path string, newName string) error {
+	parts, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	return walker.renameKey(parts, newName)
+}
+
+// MoveKey moves the key of the map item specified by path before or after the sibling key of the same map.
+// If path or sibling does not exists it returns ErrNotFound.
+// If the parent is not a map it returns ErrInvalidType.
+func (walker *YamlWalker) MoveKey(path string, placement KeyPlacement, sibling string) error {
+	parts, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	return walker.moveKey(parts, placement, sibling)
+}
+
+// InsertKeyAt inserts the node to the map at the position index, the last part of the path is the key name.
+// The index equal to the number of keys appends the key at the end.
+// Default keyStyle = 0.
+// If the parent is not a map it returns ErrInvalidType.
+// If the index is out of bounds it returns ErrInvalidRange.
+// If key name already exists it returns ErrDuplicateKey.
+func (walker *YamlWalker) InsertKeyAt(path string, index int, node *YamlWalker, keyStyle ...yaml.Style) error {
+	style := yaml.Style(0)
+	if len(keyStyle) > 0 {
+		style = keyStyle[0]
+	}
+
+	parts, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	return walker.insertKeyAt(parts, index, node, style)
+}
+
+// SortKeys sorts the keys of the map specified by path.
+// Keys are compared by less, <nil> sorts them alphabetically; the sort is stable.
+// If recursive is set the nested maps are sorted too, including maps inside sequences,
+// aliases are not followed.
+// If path does not exists it returns ErrNotFound.
+// If the node is not a map it returns ErrInvalidType.
+func (walker *YamlWalker) SortKeys(path string, recursive bool, less func(a, b string) bool) error {
+	parts, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+
+	node, err := walker.findNode(parts)
+	if err != nil {
+		return err
+	}
+	node = node.resolve()
+	if _, ok := node.data.(map[string]*YamlWalker); !ok {
+		return ErrInvalidType
+	}
+
+	if less == nil {
+		less = func(a, b string) bool { return a < b }
+	}
+	node.sortKeys(recursive, less)
+	return nil
+}
+
+// SetPathOption configures SetPath()
+type SetPathOption func(*setPathOptions)
+
+type setPathOptions struct {
+	keyStyle   yaml.Style
+	childStyle yaml.Style
+}
+
+// WithKeyStyle sets the style of keys created by SetPath()
+func WithKeyStyle(style yaml.Style) SetPathOption {
+	return func(o *setPathOptions) {
+		o.keyStyle = style
+	}
+}
+
+// WithChildStyle sets the style of maps and sequences created by SetPath()
+func WithChildStyle(style yaml.Style) SetPathOption {
+	return func(o *setPathOptions) {
+		o.childStyle = style
+	}
+}
+
+// SetPath sets the value of the node at the specified path creating all missing nodes on the way.
+// The value is set by Update(), so *YamlWalker is copied and the tree does not share nodes with it.
+// The existing node keeps its key, comments and anchor.
+//
+// Missing keys are appended to maps, null nodes on the way are turned into maps.
+// If the missing element is an index equal to the length of sequence, the new item is appended to the sequence.
+// A sequence is created instead of a map when the missing element is index 0, e.g. SetPath("a.0.b", 1)
+// on empty document creates {a: [{b: 1}]}.
+//
+// It returns ErrInvalidType if a scalar occurs in the middle of the path
+// and ErrInvalidRange if the index is out of sequence bounds.
+func (walker *YamlWalker) SetPath(path string, value interface{}, opts ...SetPathOption) error {
+	options := &setPathOptions{}
+	for _, o := range opts {
+		o(options)
+	}
+
+	parts, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	return walker.setPath(parts, value, options)
+}
+
 // Append appends the node to the map at the path.
 // Default keyStyle = 0.
-// It searches through the tree of mapping nodes (Kind == yaml.MappingNode).
-// If node Kind other than yaml.MappingNode occurs in the middle of the tree it returns ErrInvalidType.
+// It searches through the tree the same way as Get() does.
+// If the parent is a sequence the last part of the path is the index where the node is inserted,
+// the index equal to the length of sequence appends the node at the end.
 // If key name (last part of the path) already exists it returns ErrDuplicateKey.
 func (walker *YamlWalker) Append(path string, node *YamlWalker, keyStyle ...yaml.Style) error {
 	if len(path) == 0 {
@@ -216,12 +782,16 @@ func (walker *YamlWalker) Append(path string, node *YamlWalker, keyStyle ...yaml
 		style = keyStyle[0]
 	}
 
-	return walker.appendNode(walker.splitPath(path), node, style)
+	parts, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	return walker.appendNode(parts, node, style)
 }
 
 // Delete deletes the node from the map at the path.
-// It searches through the tree of mapping nodes (Kind == yaml.MappingNode).
-// If node Kind other than yaml.MappingNode occurs in the middle of the tree it returns ErrInvalidType.
+// It searches through the tree the same way as Get() does.
+// If the parent is a sequence the last part of the path is the index of the item to remove.
 // If key name (last part of the path) does not exists it returns ErrNotFound.
 // All node's children are lost.
 func (walker *YamlWalker) Delete(path string) error {
@@ -229,5 +799,9 @@ func (walker *YamlWalker) Delete(path string) error {
 		return ErrKeyMismatch
 	}
 
-	return walker.deleteNode(walker.splitPath(path))
+	parts, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	return walker.deleteNode(parts)
 }
@@ -1,9 +1,13 @@
 package yamlwalker
 
 import (
+	"bytes"
 	_ "embed"
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/yaml.v3"
@@ -22,6 +26,14 @@ var (
 	yFile []byte
 	//go:embed test_data/slice.yaml
 	sliceFile []byte
+	//go:embed test_data/comments.yaml
+	commentsFile []byte
+	//go:embed test_data/stream.yaml
+	streamFile []byte
+	//go:embed test_data/num-keys.yaml
+	numKeysFile []byte
+	//go:embed test_data/query.yaml
+	queryFile []byte
 )
 
 type YamlWalkerTestSuite struct {
@@ -325,11 +337,6 @@ func (suite *YamlWalkerTestSuite) TestUnmarshal() {
 			body: notKeyValFile,
 			err:  fmt.Errorf("yaml: line 2: could not find expected ':'"),
 		},
-		{
-			name: "alias not supported",
-			body: aliasFile,
-			err:  fmt.Errorf("line 3: unsupported node kind %d(%s)", yaml.AliasNode, decodeKind(yaml.AliasNode)),
-		},
 	}
 
 	for _, tc := range errTests {
@@ -343,6 +350,445 @@ func (suite *YamlWalkerTestSuite) TestUnmarshal() {
 	}
 }
 
+func (suite *YamlWalkerTestSuite) TestAlias() {
+	y := NewYamlWalker()
+	err := yaml.Unmarshal(aliasFile, y)
+	suite.Assert().Nil(err)
+
+	s, err := y.AsSlice("key")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(2, len(s))
+	suite.Assert().Equal("name", s[0].Anchor())
+	suite.Assert().Nil(s[0].Alias())
+	suite.Assert().Equal(s[0], s[1].Alias())
+	suite.Assert().Equal("value", s[1].Value())
+
+	data, err := yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("key:\n    - &name value\n    - *name\n", string(data))
+
+	s[0].SetAnchor("renamed")
+	data, err = yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("key:\n    - &renamed value\n    - *renamed\n", string(data))
+
+	shared := NewYamlWalker()
+	err = yaml.Unmarshal([]byte("base: &base\n  port: 80\nprod: *base\n"), shared)
+	suite.Assert().Nil(err)
+	port, err := shared.Get("prod.port")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(80, port.Value())
+	m, err := shared.AsMap("prod")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(1, len(m))
+
+	orphan := NewYamlWalker()
+	orphan.SetAlias(NewYamlWalker())
+	_, err = yaml.Marshal(orphan)
+	suite.Assert().EqualError(err, ErrInvalidAlias.Error())
+
+	var out struct{ Port int }
+	err = shared.Decode("prod", &out)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(80, out.Port)
+
+	deleted := NewYamlWalker()
+	err = yaml.Unmarshal([]byte("base: &b\n  x: 1\nother: *b\n"), deleted)
+	suite.Assert().Nil(err)
+	suite.Assert().Nil(deleted.Delete("base"))
+	_, err = yaml.Marshal(deleted)
+	suite.Assert().ErrorIs(err, ErrInvalidAlias)
+
+	recursive := NewYamlWalker()
+	err = yaml.Unmarshal([]byte("a: &x\n  b: *x\n"), recursive)
+	suite.Assert().EqualError(err, "line 2: recursive alias 'x' referenced")
+	err = yaml.Unmarshal([]byte("a: &x [1, *x]\n"), recursive)
+	suite.Assert().EqualError(err, "line 1: recursive alias 'x' referenced")
+}
+
+func (suite *YamlWalkerTestSuite) TestMergeKeyLookup() {
+	y := NewYamlWalker()
+	err := yaml.Unmarshal([]byte(`base: &b
+    image: x
+    tag: "1"
+extra: &e
+    tag: "2"
+    port: 80
+svc:
+    <<: [*b, *e]
+    tag: own
+other:
+    <<: *b
+`), y)
+	suite.Require().Nil(err)
+
+	suite.Assert().Equal("x", y.GetValue("svc.image"))
+	suite.Assert().Equal("own", y.GetValue("svc.tag"))
+	suite.Assert().Equal(80, y.GetValue("svc.port"))
+	suite.Assert().Equal("1", y.GetValue("other.tag"))
+	_, err = y.Get("other.port")
+	suite.Assert().ErrorIs(err, ErrNotFound)
+
+	s, err := y.AsString("other.image")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("x", s)
+
+	position, err := y.Position("svc.port")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(Position{Line: 6, Column: 11}, position)
+
+	nodes, err := y.Query("svc.image")
+	suite.Assert().Nil(err)
+	suite.Require().Equal(1, len(nodes))
+	suite.Assert().Equal("x", nodes[0].Value())
+
+	schemaNode := NewYamlWalker()
+	err = yaml.Unmarshal([]byte(`type: object
+required: [image, tag]
+properties:
+    port:
+        type: string
+`), schemaNode)
+	suite.Require().Nil(err)
+	schema, err := NewSchema(schemaNode)
+	suite.Require().Nil(err)
+	svc, err := y.Get("svc")
+	suite.Require().Nil(err)
+	errs := schema.Validate(svc)
+	suite.Require().Equal(1, len(errs))
+	suite.Assert().Equal("port", errs[0].Path.String())
+
+	flat := NewYamlWalker()
+	err = yaml.Unmarshal([]byte("svc:\n    tag: own\n    image: x\n    port: 80\n"), flat)
+	suite.Require().Nil(err)
+	other, err := flat.Get("svc")
+	suite.Require().Nil(err)
+	changes, err := Diff(svc, other)
+	suite.Assert().Nil(err)
+	suite.Assert().Empty(changes)
+}
+
+func (suite *YamlWalkerTestSuite) TestComments() {
+	y := NewYamlWalker()
+	err := yaml.Unmarshal(commentsFile, y)
+	suite.Assert().Nil(err)
+
+	data, err := yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(string(commentsFile), string(data))
+
+	tests := []struct {
+		name    string
+		path    string
+		comment Comment
+	}{
+		{
+			name:    "head of map",
+			path:    "service",
+			comment: Comment{Head: "# service settings"},
+		},
+		{
+			name:    "line of scalar",
+			path:    "service.name",
+			comment: Comment{Line: "# public name"},
+		},
+		{
+			name:    "head and foot",
+			path:    "service.port",
+			comment: Comment{Head: "# listening port", Foot: "# end of service"},
+		},
+		{
+			name:    "line of sequence",
+			path:    "list",
+			comment: Comment{Line: "# items"},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		suite.Run(tc.name, func() {
+			c, err := y.Comment(tc.path)
+			suite.Assert().Nil(err)
+			suite.Assert().Equal(tc.comment, c)
+		})
+	}
+
+	s, err := y.AsSlice("list")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(Comment{Head: "# first item"}, s[0].comment)
+
+	y.SetValue("service.name", "backend")
+	err = y.SetComment("service.port", Comment{Line: "# changed"})
+	suite.Assert().Nil(err)
+	err = y.SetComment("list", Comment{})
+	suite.Assert().Nil(err)
+	_, err = y.Comment("missing")
+	suite.Assert().EqualError(err, ErrNotFound.Error())
+	err = y.SetComment("missing", Comment{})
+	suite.Assert().EqualError(err, ErrNotFound.Error())
+
+	expected := `# service settings
+service:
+    name: backend # public name
+    port: 8080 # changed
+list:
+    # first item
+    - one
+    - two # second item
+`
+	data, err = yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(expected, string(data))
+}
+
+func (suite *YamlWalkerTestSuite) TestTypedScalars() {
+	body := `port: 8080
+debug: false
+ratio: 1.50
+hex: 0x1F
+empty:
+tilde: ~
+date: 2001-12-14
+quoted: "8080"
+forced: !!str 42
+ref: !Ref other
+`
+	y := NewYamlWalker()
+	err := yaml.Unmarshal([]byte(body), y)
+	suite.Assert().Nil(err)
+
+	i, err := y.AsInt("port")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(8080, i)
+	i, err = y.AsInt("hex")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(31, i)
+	b, err := y.AsBool("debug")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(false, b)
+	f, err := y.AsFloat("ratio")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(1.5, f)
+	t, err := y.AsTime("date")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(time.Date(2001, 12, 14, 0, 0, 0, 0, time.UTC), t)
+	s, err := y.AsString("quoted")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("8080", s)
+	s, err = y.AsString("forced")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("42", s)
+	s, err = y.AsString("ref")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("other", s)
+	_, err = y.AsFloat("port")
+	suite.Assert().EqualError(err, ErrInvalidType.Error())
+	_, err = y.AsTime("quoted")
+	suite.Assert().EqualError(err, ErrInvalidType.Error())
+	suite.Assert().Nil(y.GetValue("empty"))
+	suite.Assert().Nil(y.GetValue("tilde"))
+
+	tags := map[string]string{
+		"port":   "!!int",
+		"debug":  "!!bool",
+		"ratio":  "!!float",
+		"empty":  "!!null",
+		"date":   "!!timestamp",
+		"quoted": "!!str",
+		"forced": "!!str",
+		"ref":    "!Ref",
+	}
+	for path, tag := range tags {
+		node, err := y.Get(path)
+		suite.Assert().Nil(err)
+		suite.Assert().Equal(tag, node.Tag(), path)
+	}
+
+	data, err := yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(body, string(data))
+
+	y.SetValue("port", "9090")
+	y.SetValue("ratio", 2.0)
+	y.SetValue("debug", "true")
+	y.SetValue("hex", 16)
+	expected := `port: "9090"
+debug: "true"
+ratio: 2.0
+hex: 16
+`
+	data, err = yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(expected, string(data)[:len(expected)])
+
+	// the style is reset when the type changes
+	styled := NewYamlWalker()
+	err = yaml.Unmarshal([]byte("a: \"8080\"\nb: 'x'\nc: !Ref foo\nd: 'y'\n"), styled)
+	suite.Require().Nil(err)
+	styled.SetValue("a", 9090)
+	styled.SetValue("b", true)
+	styled.SetValue("c", "bar")
+	styled.SetValue("d", "z")
+	data, err = yaml.Marshal(styled)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("a: 9090\nb: true\nc: bar\nd: 'z'\n", string(data))
+}
+
+func (suite *YamlWalkerTestSuite) TestStream() {
+	stream, err := DecodeAll(bytes.NewReader(streamFile))
+	suite.Require().Nil(err)
+	suite.Assert().Equal(2, stream.Len())
+
+	first, err := stream.Document(0)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("Service", first.GetValue("kind"))
+	second, err := stream.Document(1)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("api-deployment", second.GetValue("metadata.labels.app"))
+	_, err = stream.Document(2)
+	suite.Assert().EqualError(err, ErrInvalidRange.Error())
+
+	var out bytes.Buffer
+	err = stream.EncodeAll(&out)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(string(streamFile), out.String())
+
+	third := NewYamlWalker()
+	err = yaml.Unmarshal([]byte("kind: ConfigMap\n"), third)
+	suite.Assert().Nil(err)
+	stream.Append(third)
+	err = stream.Remove(0)
+	suite.Assert().Nil(err)
+	err = stream.SetDocumentComment(1, Comment{Head: "# config"})
+	suite.Assert().Nil(err)
+	err = stream.Remove(5)
+	suite.Assert().EqualError(err, ErrInvalidRange.Error())
+	docs := stream.Documents()
+	suite.Assert().Equal(2, len(docs))
+	suite.Assert().Equal(second, docs[0])
+	suite.Assert().Equal(third, docs[1])
+
+	stream = NewYamlStream(first, third)
+	out.Reset()
+	err = stream.EncodeAll(&out)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("# the service\napiVersion: v1\nkind: Service\nmetadata:\n    name: &name api\n---\nkind: ConfigMap\n", out.String())
+
+	_, err = DecodeAll(bytes.NewReader([]byte("a: 1\n---\nb: [\n")))
+	suite.Assert().NotNil(err)
+}
+
+func (suite *YamlWalkerTestSuite) TestWalk() {
+	y := NewYamlWalker()
+	err := yaml.Unmarshal([]byte("a:\n  b: 1\n  c: [x, y]\nd: &d 2\ne: *d\n"), y)
+	suite.Require().Nil(err)
+
+	paths := []string{}
+	err = y.Walk(func(path Path, node *YamlWalker) error {
+		paths = append(paths, path.String())
+		return nil
+	})
+	suite.Assert().Nil(err)
+	suite.Assert().Equal([]string{"", "a", "a.b", "a.c", "a.c.0", "a.c.1", "d", "e"}, paths)
+
+	paths = []string{}
+	err = y.WalkPostOrder(func(path Path, node *YamlWalker) error {
+		paths = append(paths, path.String())
+		return nil
+	})
+	suite.Assert().Nil(err)
+	suite.Assert().Equal([]string{"a.b", "a.c.0", "a.c.1", "a.c", "a", "d", "e", ""}, paths)
+
+	paths = []string{}
+	err = y.Walk(func(path Path, node *YamlWalker) error {
+		paths = append(paths, path.String())
+		if path.String() == "a" {
+			return SkipChildren
+		}
+		if node.Alias() != nil {
+			return Stop
+		}
+		return nil
+	})
+	suite.Assert().Nil(err)
+	suite.Assert().Equal([]string{"", "a", "d", "e"}, paths)
+
+	failure := fmt.Errorf("failure")
+	count := 0
+	err = y.Walk(func(path Path, node *YamlWalker) error {
+		count++
+		if len(path) == 2 {
+			return failure
+		}
+		return nil
+	})
+	suite.Assert().Equal(failure, err)
+	suite.Assert().Equal(3, count)
+}
+
+func (suite *YamlWalkerTestSuite) TestQuery() {
+	y := NewYamlWalker()
+	err := yaml.Unmarshal(queryFile, y)
+	suite.Require().Nil(err)
+
+	tests := []struct {
+		expr     string
+		expected []interface{}
+	}{
+		{expr: "spec.replicas", expected: []interface{}{3}},
+		{expr: "$.spec.containers.*.image", expected: []interface{}{"api:1.0", "worker:2.1", "gateway:0.9"}},
+		{expr: "spec.containers[*].name", expected: []interface{}{"api-server", "worker", "api-gateway"}},
+		{expr: "..image", expected: []interface{}{"api:1.0", "worker:2.1", "gateway:0.9", "busybox"}},
+		{expr: "$..ports[-1]", expected: []interface{}{443, 8080}},
+		{expr: "spec.containers[1].name", expected: []interface{}{"worker"}},
+		{expr: "spec.containers.-1.name", expected: []interface{}{"api-gateway"}},
+		{expr: "spec.containers[1:].name", expected: []interface{}{"worker", "api-gateway"}},
+		{expr: "spec.containers[:-2].name", expected: []interface{}{"api-server"}},
+		{expr: `spec.containers[?(@.name =~ "^api")].image`, expected: []interface{}{"api:1.0", "gateway:0.9"}},
+		{expr: `spec.containers[?(@.name == 'worker')].image`, expected: []interface{}{"worker:2.1"}},
+		{expr: `spec.containers[?(@.ports)].name`, expected: []interface{}{"api-server", "api-gateway"}},
+		{expr: `spec.containers[?(@.ports[0] >= 443)].name`, expected: []interface{}{"api-gateway"}},
+		{expr: `spec.containers[?(@.name != "worker" && @.ports.0 < 100)].name`, expected: []interface{}{"api-server"}},
+		{expr: `spec.containers[?(@.name == "init" || @.image == "worker:2.1")].name`, expected: []interface{}{"worker"}},
+		{expr: `spec.*[?(@.name == "init")].image`, expected: []interface{}{"busybox"}},
+		{expr: `spec.containers[?(@.name == "a)]")]`, expected: []interface{}{}},
+		{expr: "spec.missing.*", expected: []interface{}{}},
+		{expr: "spec.replicas[0]", expected: []interface{}{}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		suite.Run(tc.expr, func() {
+			nodes, err := y.Query(tc.expr)
+			suite.Assert().Nil(err)
+			values := []interface{}{}
+			for _, n := range nodes {
+				values = append(values, n.Value())
+			}
+			suite.Assert().Equal(tc.expected, values)
+		})
+	}
+
+	nodes, err := y.Query("")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal([]*YamlWalker{y}, nodes)
+
+	for _, expr := range []string{"spec.", "spec[", "spec[x]", "spec[?(@.a == )]", "spec[?(name)]", `spec[?(@.a =~ 1)]`} {
+		_, err := y.Query(expr)
+		suite.Assert().EqualError(err, ErrInvalidQuery.Error(), expr)
+	}
+
+	// values of uncomparable types set by SetValue() do not match
+	y.SetValue("spec.containers.1.name", []string{"worker"})
+	nodes, err = y.Query(`spec.containers[?(@.name == "worker")].image`)
+	suite.Assert().Nil(err)
+	suite.Assert().Empty(nodes)
+	suite.Assert().Equal(0, compareValues([]string{"a"}, []string{"a"}))
+	suite.Assert().Equal(2, compareValues([]string{"a"}, []string{"b"}))
+}
+
 func (suite *YamlWalkerTestSuite) TestAsMap() {
 	y := &YamlWalker{
 		data: map[string]*YamlWalker{
@@ -1058,6 +1504,1649 @@ func (suite *YamlWalkerTestSuite) TestGetError() {
 	}
 }
 
+func (suite *YamlWalkerTestSuite) TestIndexPath() {
+	getData := func() *YamlWalker {
+		y := NewYamlWalker()
+		err := yaml.Unmarshal([]byte("servers:\n  - url: a\n    ports: [80, 443]\n  - url: b\n"), y)
+		suite.Require().Nil(err)
+		return y
+	}
+
+	y := getData()
+	tests := []struct {
+		path  string
+		value interface{}
+	}{
+		{path: "servers.0.url", value: "a"},
+		{path: "servers[0].url", value: "a"},
+		{path: "servers.1.url", value: "b"},
+		{path: "servers.-1.url", value: "b"},
+		{path: "servers[0].ports[1]", value: 443},
+		{path: "servers.0.ports.-2", value: 80},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		suite.Run(tc.path, func() {
+			suite.Assert().Equal(tc.value, y.GetValue(tc.path))
+		})
+	}
+
+	_, err := y.Get("servers.2")
+	suite.Assert().EqualError(err, ErrInvalidRange.Error())
+	_, err = y.Get("servers.-3")
+	suite.Assert().EqualError(err, ErrInvalidRange.Error())
+	_, err = y.Get("servers.url")
+	suite.Assert().EqualError(err, ErrInvalidType.Error())
+
+	m, err := y.AsMap("servers.0")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(2, len(m))
+
+	y.SetValue("servers[1].url", "c")
+	suite.Assert().Equal("c", y.GetValue("servers.1.url"))
+
+	node := NewYamlWalker()
+	node.Update(8080)
+	err = y.Insert("servers.0.ports", 1, node)
+	suite.Assert().Nil(err)
+	err = y.Remove("servers.0.ports", 0)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(8080, y.GetValue("servers.0.ports.0"))
+	suite.Assert().Equal(443, y.GetValue("servers.0.ports.1"))
+
+	err = y.Append("servers.1.name", node)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(8080, y.GetValue("servers.1.name"))
+
+	item := NewYamlWalker()
+	item.Update("first")
+	err = y.Append("servers.0", item)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("first", y.GetValue("servers.0"))
+	err = y.Append("servers.3", item)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("first", y.GetValue("servers.-1"))
+	err = y.Append("servers.5", item)
+	suite.Assert().EqualError(err, ErrInvalidRange.Error())
+
+	y = getData()
+	err = y.Delete("servers.0.ports")
+	suite.Assert().Nil(err)
+	err = y.Delete("servers.0")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("b", y.GetValue("servers.0.url"))
+	err = y.Delete("servers.1")
+	suite.Assert().EqualError(err, ErrInvalidRange.Error())
+	err = y.Delete("servers.x")
+	suite.Assert().EqualError(err, ErrInvalidType.Error())
+}
+
+func (suite *YamlWalkerTestSuite) TestParsePath() {
+	tests := []struct {
+		path     string
+		expected Path
+		str      string
+	}{
+		{path: "", expected: Path{}, str: ""},
+		{path: "a.b.c", expected: Path{"a", "b", "c"}, str: "a.b.c"},
+		{path: "a[0].b[1][-1]", expected: Path{"a", "0", "b", "1", "-1"}, str: "a.0.b.1.-1"},
+		{path: "[0]", expected: Path{"0"}, str: "0"},
+		{path: `labels."app.kubernetes.io/name"`, expected: Path{"labels", "app.kubernetes.io/name"}, str: `labels."app.kubernetes.io/name"`},
+		{path: `'1.0'.type`, expected: Path{"1.0", "type"}, str: `"1.0".type`},
+		{path: `'it''s'`, expected: Path{"it's"}, str: `"it's"`},
+		{path: `"say \"hi\""[2]`, expected: Path{`say "hi"`, "2"}, str: `"say \"hi\"".2`},
+		{path: `a\.b.c\\d`, expected: Path{"a.b", `c\d`}, str: `"a.b"."c\\d"`},
+		{path: `a..b`, expected: Path{"a", "", "b"}, str: `a."".b`},
+		{path: `""`, expected: Path{""}, str: `""`},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		suite.Run(tc.path, func() {
+			p, err := ParsePath(tc.path)
+			suite.Assert().Nil(err)
+			suite.Assert().Equal(tc.expected, p)
+			suite.Assert().Equal(tc.str, p.String())
+			p, err = ParsePath(p.String())
+			suite.Assert().Nil(err)
+			suite.Assert().Equal(tc.expected, p)
+		})
+	}
+
+	for _, path := range []string{`"open`, `a[0`, `a[x]`, `"a"b`, `a\`, `a[0]b`} {
+		_, err := ParsePath(path)
+		suite.Assert().EqualError(err, ErrInvalidPath.Error(), path)
+	}
+
+	y := NewYamlWalker()
+	err := yaml.Unmarshal([]byte("labels:\n  app.kubernetes.io/name: api\n  '': empty\n"), y)
+	suite.Assert().Nil(err)
+	s, err := y.AsString(Path{"labels", "app.kubernetes.io/name"}.String())
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("api", s)
+	s, err = y.AsString(`labels.""`)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("empty", s)
+	_, err = y.Get(`labels."app`)
+	suite.Assert().EqualError(err, ErrInvalidPath.Error())
+	suite.Assert().Equal(Path{"a", "b", "0"}, Path{"a"}.Append("b", Index(0)))
+}
+
+func (suite *YamlWalkerTestSuite) TestSetPath() {
+	y := NewYamlWalker()
+	err := y.SetPath("a.b.c.d", 1)
+	suite.Assert().Nil(err)
+	err = y.SetPath("a.b.list.0.name", "first", WithKeyStyle(yaml.DoubleQuotedStyle))
+	suite.Assert().Nil(err)
+	err = y.SetPath("a.b.list[1].name", "second")
+	suite.Assert().Nil(err)
+	err = y.SetPath("a.b.list.0.name", "changed")
+	suite.Assert().Nil(err)
+	err = y.SetPath("a.ports.8080", "http", WithChildStyle(yaml.FlowStyle))
+	suite.Assert().Nil(err)
+	err = y.SetPath("a.empty.x", true)
+	suite.Assert().Nil(err)
+	err = y.SetPath("a.none", nil)
+	suite.Assert().Nil(err)
+	err = y.SetPath("a.none.0", "item")
+	suite.Assert().Nil(err)
+
+	node := NewYamlWalker(yaml.FlowStyle)
+	node.Update([]*YamlWalker{{data: 1}, {data: 2}})
+	err = y.SetPath("a.b.c", node)
+	suite.Assert().Nil(err)
+
+	expected := `a:
+    b:
+        c: [1, 2]
+        "list":
+            - "name": changed
+            - name: second
+    ports: {8080: http}
+    empty:
+        x: true
+    none:
+        - item
+`
+	data, err := yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(expected, string(data))
+
+	err = y.SetPath("a.b.c.d", 1)
+	suite.Assert().EqualError(err, ErrInvalidType.Error())
+	err = y.SetPath("a.b.list.5", 1)
+	suite.Assert().EqualError(err, ErrInvalidRange.Error())
+	err = y.SetPath("a.ports.8080.name", 1)
+	suite.Assert().EqualError(err, ErrInvalidType.Error())
+	err = y.SetPath("a.[", 1)
+	suite.Assert().EqualError(err, ErrInvalidPath.Error())
+
+	err = y.SetPath("", "scalar")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("scalar", y.Value())
+
+	// the node is copied, later changes of it do not reach the tree
+	source := NewMapping()
+	suite.Require().Nil(source.Append("x", NewScalar(1, 0)))
+	suite.Assert().Nil(y.SetPath("", source))
+	suite.Assert().Nil(y.SetPath("copy", source))
+	suite.Assert().Nil(source.Append("z", NewScalar(2, 0)))
+	data, err = yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("x: 1\ncopy:\n    x: 1\n", string(data))
+}
+
+func (suite *YamlWalkerTestSuite) TestConvert() {
+	type container struct {
+		Name  string   `yaml:"name"`
+		Image string   `yaml:"image,omitempty"`
+		Ports []int    `yaml:"ports,flow"`
+		Args  []string `yaml:"args,omitempty"`
+	}
+
+	y := NewYamlWalker()
+	err := yaml.Unmarshal([]byte(`# containers
+containers:
+    - ports: [80]
+      # the name
+      name: 'api' # quoted
+      image: api:1.0
+      extra: true
+    - name: worker
+      ports: [1]
+base: &base
+    name: base
+    ports: [22]
+alias: *base
+`), y)
+	suite.Require().Nil(err)
+
+	var c container
+	err = y.Decode("containers.0", &c)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(container{Name: "api", Image: "api:1.0", Ports: []int{80}}, c)
+	var all []container
+	err = y.Decode("containers", &all)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(2, len(all))
+	var base container
+	err = y.Decode("alias", &base)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(container{Name: "base", Ports: []int{22}}, base)
+	err = y.Decode("missing", &c)
+	suite.Assert().EqualError(err, ErrNotFound.Error())
+
+	node, err := FromValue(container{Name: "db", Ports: []int{5432}, Args: []string{"-v"}})
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("db", node.GetValue("name"))
+	suite.Assert().Equal(5432, node.GetValue("ports.0"))
+	data, err := yaml.Marshal(node)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("name: db\nports: [5432]\nargs:\n    - -v\n", string(data))
+
+	err = y.Assign("containers", []container{
+		{Name: "api", Image: "api:2.0", Ports: []int{80, 443}},
+		{Name: "worker", Ports: []int{1}, Args: []string{"run"}},
+		{Name: "new"},
+	})
+	suite.Assert().Nil(err)
+	err = y.Delete("base")
+	suite.Assert().Nil(err)
+	err = y.Delete("alias")
+	suite.Assert().Nil(err)
+	expected := `# containers
+containers:
+    - ports: [80, 443]
+      # the name
+      name: 'api' # quoted
+      image: api:2.0
+    - name: worker
+      ports: [1]
+      args:
+        - run
+    - name: new
+      ports: []
+`
+	data, err = yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(expected, string(data))
+
+	err = y.Assign("missing", 1)
+	suite.Assert().EqualError(err, ErrNotFound.Error())
+}
+
+func (suite *YamlWalkerTestSuite) TestMerge() {
+	parse := func(body string) *YamlWalker {
+		y := NewYamlWalker()
+		err := yaml.Unmarshal([]byte(body), y)
+		suite.Require().Nil(err)
+		return y
+	}
+	base := `name: app # the name
+replicas: 1
+tags: [a, b]
+debug: true
+containers:
+    - name: api
+      image: api:1.0
+    - name: worker
+      image: worker:1.0
+`
+	override := `replicas: 3
+'owner': team
+tags: [b, c]
+debug: ~
+containers:
+    - name: worker
+      image: worker:2.0
+    - name: cron
+      image: cron:1.0
+`
+
+	tests := []struct {
+		name     string
+		opts     []MergeOption
+		expected string
+	}{
+		{
+			name: "default",
+			expected: `name: app # the name
+replicas: 3
+tags: [b, c]
+debug: ~
+containers:
+    - name: worker
+      image: worker:2.0
+    - name: cron
+      image: cron:1.0
+'owner': team
+`,
+		},
+		{
+			name: "append, keep existing and null deletes",
+			opts: []MergeOption{WithSequenceStrategy(SequenceAppend), WithKeepExisting(), WithNullDeletes()},
+			expected: `name: app # the name
+replicas: 1
+tags: [a, b, b, c]
+containers:
+    - name: api
+      image: api:1.0
+    - name: worker
+      image: worker:1.0
+    - name: worker
+      image: worker:2.0
+    - name: cron
+      image: cron:1.0
+'owner': team
+`,
+		},
+		{
+			name: "dedupe and merge by key",
+			opts: []MergeOption{WithSequenceStrategy(SequenceDedupe), WithPathStrategy("containers", SequenceMergeByKey, "name")},
+			expected: `name: app # the name
+replicas: 3
+tags: [a, b, c]
+debug: ~
+containers:
+    - name: api
+      image: api:1.0
+    - name: worker
+      image: worker:2.0
+    - name: cron
+      image: cron:1.0
+'owner': team
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		suite.Run(tc.name, func() {
+			y := parse(base)
+			other := parse(override)
+			err := y.Merge(other, tc.opts...)
+			suite.Assert().Nil(err)
+			data, err := yaml.Marshal(y)
+			suite.Assert().Nil(err)
+			suite.Assert().Equal(tc.expected, string(data))
+
+			// the trees do not share nodes
+			other.SetValue("containers.1.image", "changed")
+			suite.Assert().NotEqual("changed", y.GetValue("containers.-1.image"))
+		})
+	}
+
+	y := parse("defaults: &d\n  a: 1\nservice: *d\n")
+	err := y.Merge(parse("service:\n  b: 2\n"))
+	suite.Assert().Nil(err)
+	data, err := yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("defaults: &d\n    a: 1\nservice:\n    a: 1\n    b: 2\n", string(data))
+
+	y = parse("list:\n  - name: a\n    x: [1]\n")
+	err = y.Merge(parse("list:\n  - name: a\n    x: [2]\n"),
+		WithPathStrategy("list", SequenceMergeByKey, "name"), WithPathStrategy("list.*.x", SequenceAppend))
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(1, y.GetValue("list.0.x.0"))
+	suite.Assert().Equal(2, y.GetValue("list.0.x.1"))
+
+	// values of uncomparable types set by SetValue() are compared deeply
+	a, b := parse("x: 1\nl: [1]\n"), parse("x: 1\nl: [1]\n")
+	a.SetValue("x", []string{"a"})
+	b.SetValue("x", []string{"a"})
+	changes, err := Diff(a, b)
+	suite.Assert().Nil(err)
+	suite.Assert().Empty(changes)
+	b.SetValue("x", []string{"b"})
+	changes, err = Diff(a, b)
+	suite.Assert().Nil(err)
+	suite.Assert().Len(changes, 1)
+	a.SetValue("l.0", []string{"a"})
+	b.SetValue("l.0", []string{"a"})
+	err = a.Merge(b, WithSequenceStrategy(SequenceDedupe))
+	suite.Assert().Nil(err)
+	suite.Assert().Len(a.GetValue("l"), 1)
+
+	err = a.Merge(b, WithPathStrategy("l[", SequenceAppend))
+	suite.Assert().ErrorIs(err, ErrInvalidPath)
+	suite.Assert().Len(a.GetValue("l"), 1)
+}
+
+func (suite *YamlWalkerTestSuite) TestDiff() {
+	parse := func(body string) *YamlWalker {
+		y := NewYamlWalker()
+		err := yaml.Unmarshal([]byte(body), y)
+		suite.Require().Nil(err)
+		return y
+	}
+	type change struct {
+		op   ChangeOp
+		path string
+		from string
+	}
+	collect := func(changes []Change, err error) []change {
+		suite.Require().Nil(err)
+		result := []change{}
+		for _, c := range changes {
+			result = append(result, change{op: c.Op, path: c.Path.String(), from: c.From.String()})
+		}
+		return result
+	}
+
+	a := parse(`name: app
+replicas: 1
+tags: [a, b]
+containers:
+    - name: api
+      image: api:1.0
+    - name: worker
+      image: worker:1.0
+`)
+	b := parse(`replicas: 3
+"name": app
+tags: [a]
+containers:
+    - name: worker
+      image: worker:2.0
+    - name: cron
+      image: cron:1.0
+debug: true
+`)
+
+	suite.Run("by index", func() {
+		suite.Assert().Equal([]change{
+			{op: ChangeModified, path: "replicas", from: "replicas"},
+			{op: ChangeRemoved, path: "tags.1", from: "tags.1"},
+			{op: ChangeModified, path: "containers.0.name", from: "containers.0.name"},
+			{op: ChangeModified, path: "containers.0.image", from: "containers.0.image"},
+			{op: ChangeModified, path: "containers.1.name", from: "containers.1.name"},
+			{op: ChangeModified, path: "containers.1.image", from: "containers.1.image"},
+			{op: ChangeAdded, path: "debug"},
+		}, collect(Diff(a, b)))
+	})
+
+	suite.Run("by key with format", func() {
+		changes, err := Diff(a, b, WithDiffKey("containers", "name"), WithFormatChanges())
+		suite.Assert().Equal([]change{
+			{op: ChangeMoved, path: "replicas", from: "replicas"},
+			{op: ChangeModified, path: "replicas", from: "replicas"},
+			{op: ChangeMoved, path: "name", from: "name"},
+			{op: ChangeModified, path: "name", from: "name"},
+			{op: ChangeRemoved, path: "tags.1", from: "tags.1"},
+			{op: ChangeMoved, path: "containers.0", from: "containers.1"},
+			{op: ChangeModified, path: "containers.0.image", from: "containers.1.image"},
+			{op: ChangeAdded, path: "containers.1"},
+			{op: ChangeRemoved, path: "containers.0", from: "containers.0"},
+			{op: ChangeAdded, path: "debug"},
+		}, collect(changes, err))
+		suite.Assert().Equal("worker:1.0", changes[6].Old.Value())
+		suite.Assert().Equal("worker:2.0", changes[6].New.Value())
+	})
+
+	suite.Run("equal", func() {
+		suite.Assert().Empty(collect(Diff(a, a, WithFormatChanges())))
+		suite.Assert().Empty(collect(Diff(parse("a: &x {b: 1}\nc: *x\n"), parse("a: {b: 1}\nc: {b: 1}\n"))))
+		suite.Assert().Equal([]change{{op: ChangeModified, path: "a", from: "a"}},
+			collect(Diff(parse("a: 1\n"), parse("a: '1'\n"))))
+		suite.Assert().Equal("modified", ChangeModified.String())
+	})
+
+	suite.Run("invalid pattern", func() {
+		_, err := Diff(a, b, WithDiffKey("containers[", "name"))
+		suite.Assert().ErrorIs(err, ErrInvalidPath)
+	})
+}
+
+func (suite *YamlWalkerTestSuite) TestApplyPatch() {
+	source := `# service
+name: app # the name
+spec:
+    replicas: 1
+    ports: [80, 443]
+    'a/b': x
+defaults: &d
+    level: info
+logging: *d
+`
+
+	tests := []struct {
+		name     string
+		patch    string
+		expected string
+		err      error
+	}{
+		{
+			name: "json",
+			patch: `[
+	{"op": "test", "path": "/name", "value": "app"},
+	{"op": "replace", "path": "/spec/replicas", "value": 3},
+	{"op": "add", "path": "/spec/ports/-", "value": 8080},
+	{"op": "add", "path": "/spec/ports/0", "value": 22},
+	{"op": "remove", "path": "/spec/a~1b"},
+	{"op": "add", "path": "/spec/labels", "value": {"app": "web"}}
+]`,
+			expected: `# service
+name: app # the name
+spec:
+    replicas: 3
+    ports: [22, 80, 443, 8080]
+    labels:
+        app: web
+defaults: &d
+    level: info
+logging: *d
+`,
+		},
+		{
+			name: "yaml",
+			patch: `- op: move
+  from: /spec/replicas
+  path: /replicas
+- op: copy
+  from: /spec/ports
+  path: /ports
+- op: replace
+  path: /name
+  value: web
+- op: add
+  path: /logging/level
+  value: debug
+`,
+			expected: `# service
+name: web # the name
+spec:
+    ports: [80, 443]
+    'a/b': x
+defaults: &d
+    level: info
+logging:
+    level: debug
+replicas: 1
+ports: [80, 443]
+`,
+		},
+		{
+			name:  "replace anchored node",
+			patch: `[{"op": "replace", "path": "/defaults", "value": {"level": "warn"}}, {"op": "add", "path": "/name", "value": "web"}]`,
+			expected: `# service
+name: web # the name
+spec:
+    replicas: 1
+    ports: [80, 443]
+    'a/b': x
+defaults: &d
+    level: warn
+logging: *d
+`,
+		},
+		{
+			name:     "numbers compared by value",
+			patch:    `[{"op": "test", "path": "/spec/replicas", "value": 1.0}, {"op": "test", "path": "/spec/ports", "value": [80.0, 443]}]`,
+			expected: source,
+		},
+		{
+			name:  "failed test",
+			patch: `[{"op": "remove", "path": "/name"}, {"op": "test", "path": "/spec/replicas", "value": 2}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "string is not number",
+			patch: `[{"op": "test", "path": "/spec/replicas", "value": "1"}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "missing path",
+			patch: `[{"op": "remove", "path": "/name"}, {"op": "replace", "path": "/spec/missing", "value": 2}]`,
+			err:   ErrNotFound,
+		},
+		{
+			name:  "index out of range",
+			patch: `[{"op": "add", "path": "/spec/ports/3", "value": 2}]`,
+			err:   ErrInvalidRange,
+		},
+		{
+			name:  "invalid index",
+			patch: `[{"op": "remove", "path": "/spec/ports/01"}]`,
+			err:   ErrInvalidType,
+		},
+		{
+			name:  "unknown op",
+			patch: `[{"op": "drop", "path": "/name"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "missing value",
+			patch: `[{"op": "add", "path": "/name"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "move into child",
+			patch: `[{"op": "move", "from": "/spec", "path": "/spec/inner"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "not a patch",
+			patch: `{"op": "remove"}`,
+			err:   ErrInvalidPatch,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		suite.Run(tc.name, func() {
+			y := NewYamlWalker()
+			err := yaml.Unmarshal([]byte(source), y)
+			suite.Require().Nil(err)
+
+			err = y.ApplyPatch([]byte(tc.patch))
+			data, e := yaml.Marshal(y)
+			suite.Assert().Nil(e)
+			if tc.err != nil {
+				suite.Assert().ErrorIs(err, tc.err)
+				suite.Assert().Equal(source, string(data))
+				return
+			}
+			suite.Assert().Nil(err)
+			suite.Assert().Equal(tc.expected, string(data))
+		})
+	}
+}
+
+func (suite *YamlWalkerTestSuite) TestMergePatch() {
+	parse := func(body string) *YamlWalker {
+		y := NewYamlWalker()
+		err := yaml.Unmarshal([]byte(body), y)
+		suite.Require().Nil(err)
+		return y
+	}
+	marshal := func(y *YamlWalker) string {
+		data, err := yaml.Marshal(y)
+		suite.Require().Nil(err)
+		return string(data)
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		patch    string
+		expected string
+	}{
+		{
+			name: "keys keep position",
+			target: `title: Goodbye! # greeting
+author:
+    givenName: John
+    familyName: Doe
+tags: [example, sample]
+content: This will be unchanged
+`,
+			patch: `{"title": "Hello!", "phoneNumber": "+01-123-456-7890", "author": {"familyName": null}, "tags": ["example"]}`,
+			expected: `title: Hello! # greeting
+author:
+    givenName: John
+tags: ["example"]
+content: This will be unchanged
+"phoneNumber": "+01-123-456-7890"
+`,
+		},
+		{
+			name:     "new map drops nulls",
+			target:   "a: b\n",
+			patch:    "a:\n  c: ~\n  d: e\n",
+			expected: "a:\n    d: e\n",
+		},
+		{
+			name:     "nulls in sequence",
+			target:   "a: [b]\n",
+			patch:    "a: [~]\nb: ~\n",
+			expected: "a: [~]\n",
+		},
+		{
+			name:     "not a map",
+			target:   "a: b\n",
+			patch:    "[c]\n",
+			expected: "- c\n",
+		},
+		{
+			name:     "alias",
+			target:   "a: &x {b: 1}\nc: *x\n",
+			patch:    "c: {b: 2}\n",
+			expected: "a: &x {b: 1}\nc: {b: 2}\n",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		suite.Run(tc.name, func() {
+			y := parse(tc.target)
+			err := y.ApplyMergePatch(parse(tc.patch))
+			suite.Assert().Nil(err)
+			suite.Assert().Equal(tc.expected, marshal(y))
+		})
+	}
+
+	from := parse("a: 1\nb:\n  c: 2\n  d: 3\ne: [1]\nf: x\n")
+	to := parse("a: 1\nb:\n  c: 4\n  d: 3\ne: [1, 2]\ng: y\n")
+	patch := CreateMergePatch(from, to)
+	suite.Assert().Equal("b:\n    c: 4\ne: [1, 2]\ng: y\nf: null\n", marshal(patch))
+	err := from.ApplyMergePatch(patch)
+	suite.Assert().Nil(err)
+	changes, err := Diff(from, to)
+	suite.Assert().Nil(err)
+	suite.Assert().Empty(changes)
+	suite.Assert().Equal("- 1\n", marshal(CreateMergePatch(to, parse("[1]"))))
+}
+
+func (suite *YamlWalkerTestSuite) TestStrategicMergePatch() {
+	parse := func(body string) *YamlWalker {
+		y := NewYamlWalker()
+		err := yaml.Unmarshal([]byte(body), y)
+		suite.Require().Nil(err)
+		return y
+	}
+	source := `spec:
+    replicas: 1
+    containers:
+        - name: api # main
+          image: api:1.0
+          ports: [80, 443]
+          env:
+            - name: LEVEL
+              value: info
+            - name: DEBUG
+              value: "false"
+        - name: worker
+          image: worker:1.0
+    volumes:
+        - name: data
+        - name: logs
+    tolerations: [a, b]
+`
+	opts := []StrategicMergeOption{
+		WithMergeKey("spec.containers", "name"),
+		WithMergeKey("spec.containers.*.env", "name"),
+		WithMergeKey("spec.containers.*.ports", ""),
+	}
+
+	tests := []struct {
+		name     string
+		patch    string
+		expected string
+		err      error
+	}{
+		{
+			name: "merge by key",
+			patch: `spec:
+    containers:
+        - name: worker
+          image: worker:2.0
+        - name: api
+          ports: [443, 8080]
+          env:
+            - name: DEBUG
+              $patch: delete
+            - name: LEVEL
+              value: debug
+            - name: EXTRA
+              value: "1"
+        - name: cron
+          image: cron:1.0
+          env:
+            - name: A
+              $patch: delete
+    volumes:
+        - name: cache
+    tolerations: ~
+`,
+			expected: `spec:
+    replicas: 1
+    containers:
+        - name: api # main
+          image: api:1.0
+          ports: [80, 443, 8080]
+          env:
+            - name: LEVEL
+              value: debug
+            - name: EXTRA
+              value: "1"
+        - name: worker
+          image: worker:2.0
+        - name: cron
+          image: cron:1.0
+          env: []
+    volumes:
+        - name: cache
+`,
+		},
+		{
+			name: "directives",
+			patch: `spec:
+    $setElementOrder/containers:
+        - name: worker
+        - name: api
+    containers:
+        - name: api
+          env:
+            - $patch: replace
+            - name: ONLY
+              value: x
+    volumes:
+        $patch: delete
+    replicas:
+        $patch: replace
+        min: 1
+`,
+			expected: `spec:
+    replicas:
+        min: 1
+    containers:
+        - name: worker
+          image: worker:1.0
+        - name: api # main
+          image: api:1.0
+          ports: [80, 443]
+          env:
+            - name: ONLY
+              value: x
+    tolerations: [a, b]
+`,
+		},
+		{
+			name: "order by key values",
+			patch: `spec:
+    $setElementOrder/containers: [worker, api]
+    $setElementOrder/volumes: [logs, data]
+`,
+			expected: `spec:
+    replicas: 1
+    containers:
+        - name: worker
+          image: worker:1.0
+        - name: api # main
+          image: api:1.0
+          ports: [80, 443]
+          env:
+            - name: LEVEL
+              value: info
+            - name: DEBUG
+              value: "false"
+    volumes:
+        - name: data
+        - name: logs
+    tolerations: [a, b]
+`,
+		},
+		{
+			name:  "invalid directive",
+			patch: "spec:\n  replicas: 2\n  containers:\n    - name: api\n      $patch: drop\n",
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "unsupported directive",
+			patch: "spec:\n  replicas: 2\n  $retainKeys: [replicas]\n",
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "delete root",
+			patch: "$patch: delete\nspec:\n  replicas: 2\n",
+			err:   ErrInvalidPatch,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		suite.Run(tc.name, func() {
+			y := parse(source)
+			err := y.ApplyStrategicMergePatch(parse(tc.patch), opts...)
+			data, e := yaml.Marshal(y)
+			suite.Assert().Nil(e)
+			if tc.err != nil {
+				suite.Assert().ErrorIs(err, tc.err)
+				suite.Assert().Equal(source, string(data))
+				return
+			}
+			suite.Assert().Nil(err)
+			suite.Assert().Equal(tc.expected, string(data))
+		})
+	}
+
+	y := parse(source)
+	err := y.ApplyStrategicMergePatch(parse("spec:\n  replicas: 2\n"), WithMergeKey("spec.containers[", "name"))
+	suite.Assert().ErrorIs(err, ErrInvalidPath)
+	suite.Assert().Equal(1, y.GetValue("spec.replicas"))
+}
+
+func (suite *YamlWalkerTestSuite) TestValidate() {
+	parse := func(body string) *YamlWalker {
+		y := NewYamlWalker()
+		err := yaml.Unmarshal([]byte(body), y)
+		suite.Require().Nil(err)
+		return y
+	}
+	schema, err := NewSchema(parse(`
+$schema: https://json-schema.org/draft/2020-12/schema
+type: object
+required: [name, spec]
+additionalProperties: false
+properties:
+    name:
+        type: string
+        pattern: "^[a-z]+$"
+    spec:
+        type: object
+        properties:
+            replicas:
+                type: integer
+            mode:
+                enum: [fast, safe]
+            ports:
+                type: array
+                items:
+                    $ref: "#/$defs/port"
+            tls:
+                type: boolean
+            cert:
+                type: string
+        if:
+            properties:
+                tls:
+                    const: ignored
+                    enum: [true]
+            required: [tls]
+        then:
+            required: [cert]
+    owner:
+        oneOf:
+            - type: string
+            - type: object
+              required: [team]
+$defs:
+    port:
+        anyOf:
+            - type: integer
+            - type: string
+              pattern: "^[0-9]+/(tcp|udp)$"
+`))
+	suite.Require().Nil(err)
+
+	valid := parse("name: app\nspec:\n  replicas: 2.0\n  ports: [80, 53/udp]\n  tls: true\n  cert: x\nowner: me\n")
+	suite.Assert().Empty(valid.Validate(schema))
+
+	invalid := parse(`name: App
+spec:
+    replicas: "2"
+    mode: slow
+    ports:
+        - 80
+        - http
+    tls: true
+owner:
+    name: me
+extra: 1
+`)
+	type result struct {
+		path    string
+		line    int
+		keyword string
+	}
+	results := []result{}
+	for _, e := range schema.Validate(invalid) {
+		results = append(results, result{path: e.Path.String(), line: e.Line, keyword: e.Keyword})
+	}
+	suite.Assert().Equal([]result{
+		{path: "name", line: 1, keyword: "pattern"},
+		{path: "spec.replicas", line: 3, keyword: "type"},
+		{path: "spec.mode", line: 4, keyword: "enum"},
+		{path: "spec.ports.1", line: 7, keyword: "anyOf"},
+		{path: "spec", line: 3, keyword: "required"},
+		{path: "owner", line: 10, keyword: "oneOf"},
+		{path: "extra", line: 11, keyword: "additionalProperties"},
+	}, results)
+	errs := schema.Validate(parse("[]"))
+	suite.Assert().Len(errs, 1)
+	suite.Assert().Equal("line 1: must be object, got array", errs[0].Error())
+	errs = schema.Validate(invalid)
+	suite.Assert().Equal("line 3: spec.replicas: must be integer, got string", errs[1].Error())
+
+	for _, s := range []string{
+		"type: text",
+		"required: name",
+		"pattern: '('",
+		"items: 1",
+		"anyOf: []",
+		"$ref: '#/$defs/missing'",
+		"$ref: other.json",
+	} {
+		_, err := NewSchema(parse(s))
+		suite.Assert().ErrorIs(err, ErrInvalidSchema, s)
+	}
+	_, err = NewSchema(parse("$defs:\n  node:\n    items:\n      $ref: '#/$defs/node'\n$ref: '#/$defs/node'\n"))
+	suite.Assert().Nil(err)
+
+	// cycles not consuming any value would be validated forever
+	for _, s := range []string{
+		"$ref: '#'",
+		"$ref: '#/$defs/a'\n$defs:\n  a:\n    $ref: '#'\n",
+		"$ref: '#/$defs/a'\n$defs:\n  a:\n    allOf:\n      - $ref: '#/$defs/b'\n  b:\n    anyOf:\n      - type: string\n      - $ref: '#/$defs/a'\n",
+	} {
+		_, err := NewSchema(parse(s))
+		suite.Assert().ErrorIs(err, ErrInvalidSchema, s)
+	}
+}
+
+func (suite *YamlWalkerTestSuite) TestPosition() {
+	y := NewYamlWalker()
+	err := yaml.Unmarshal([]byte(`# head
+name: app
+spec:
+  base: &b {x: 1}
+  ports:
+    - 80
+    -   443
+  ref: *b
+`), y)
+	suite.Require().Nil(err)
+
+	tests := []struct {
+		path string
+		node Position
+		key  Position
+		err  error
+	}{
+		{path: "", node: Position{Line: 2, Column: 1}},
+		{path: "name", node: Position{Line: 2, Column: 7}, key: Position{Line: 2, Column: 1}},
+		{path: "spec", node: Position{Line: 4, Column: 3}, key: Position{Line: 3, Column: 1}},
+		{path: "spec.base.x", node: Position{Line: 4, Column: 16}, key: Position{Line: 4, Column: 13}},
+		{path: "spec.ports.1", node: Position{Line: 7, Column: 9}},
+		{path: "spec.ports.-2", node: Position{Line: 6, Column: 7}},
+		{path: "spec.ref", node: Position{Line: 8, Column: 8}, key: Position{Line: 8, Column: 3}},
+		{path: "spec.ref.x", node: Position{Line: 4, Column: 16}, key: Position{Line: 4, Column: 13}},
+		{path: "spec.missing", err: ErrNotFound},
+		{path: "spec.ports.2", err: ErrInvalidRange},
+		{path: "name.x", err: ErrInvalidType},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		suite.Run(tc.path, func() {
+			node, err := y.Position(tc.path)
+			suite.Assert().ErrorIs(err, tc.err)
+			suite.Assert().Equal(tc.node, node)
+			key, err := y.KeyPosition(tc.path)
+			suite.Assert().ErrorIs(err, tc.err)
+			suite.Assert().Equal(tc.key, key)
+		})
+	}
+
+	err = y.Append("spec.added", NewYamlWalker())
+	suite.Assert().Nil(err)
+	node, err := y.Position("spec.added")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(Position{}, node)
+}
+
+func (suite *YamlWalkerTestSuite) TestClone() {
+	parse := func(body string) *YamlWalker {
+		y := NewYamlWalker()
+		err := yaml.Unmarshal([]byte(body), y)
+		suite.Require().Nil(err)
+		return y
+	}
+	marshal := func(y *YamlWalker) string {
+		data, err := yaml.Marshal(y)
+		suite.Require().Nil(err)
+		return string(data)
+	}
+
+	source := parse("base: &b {x: 1}\nspec:\n  # ports\n  zport: 80\n  'host': [a, b]\n  ref: *b\n  out: *b\n")
+	spec, err := source.Get("spec")
+	suite.Require().Nil(err)
+
+	clone := spec.Clone()
+	suite.Assert().Equal("# ports\nzport: 80\n'host': [a, b]\nref: {x: 1}\nout: {x: 1}\n", marshal(clone))
+	clone.SetValue("zport", 8080)
+	suite.Assert().Equal(80, source.GetValue("spec.zport"))
+
+	target := parse("name: app # the name\nspec: ~ # the spec\n")
+	err = target.Set("spec", spec)
+	suite.Assert().Nil(err)
+	name, err := parse("v: 'web'").Get("v")
+	suite.Require().Nil(err)
+	err = target.Set("name", name)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("name: 'web' # the name\nspec:\n    # ports\n    zport: 80\n    'host': [a, b]\n    ref: {x: 1}\n    out: {x: 1}\n", marshal(target))
+
+	// the trees do not share nodes
+	source.SetValue("spec.host.0", "changed")
+	source.SetValue("base.x", 2)
+	suite.Assert().Equal("a", target.GetValue("spec.host.0"))
+	suite.Assert().Equal(1, target.GetValue("spec.ref.x"))
+
+	// aliases inside the subtree refer to the copies
+	whole := source.Clone()
+	whole.SetValue("base.x", 3)
+	suite.Assert().Equal(3, whole.GetValue("spec.ref.x"))
+	suite.Assert().Equal(2, source.GetValue("spec.ref.x"))
+
+	// keys of Go map are sorted
+	target.SetValue("spec", spec.Value())
+	suite.Assert().Equal("name: 'web' # the name\nspec:\n    host: [changed, b]\n    out: {x: 2}\n    ref: {x: 2}\n    zport: 80\n", marshal(target))
+
+	// the node set to itself or to its ancestor is copied before it is changed
+	y := parse("a:\n  b: 1\nc: 2\n")
+	a, err := y.Get("a")
+	suite.Require().Nil(err)
+	err = y.Set("a", a)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("a:\n    b: 1\nc: 2\n", marshal(y))
+	err = y.Set("a", y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("a:\n    a:\n        b: 1\n    c: 2\nc: 2\n", marshal(y))
+	a.Update(a.Value())
+	suite.Assert().Equal(2, y.GetValue("a.c"))
+	suite.Assert().Equal(1, y.GetValue("a.a.b"))
+}
+
+func (suite *YamlWalkerTestSuite) TestEntries() {
+	y := NewYamlWalker()
+	err := yaml.Unmarshal([]byte("base: &b {x: 1}\nspec:\n  zeta: 1\n  'alpha': *b\n  mid: [1]\nlist: [1]\n"), y)
+	suite.Require().Nil(err)
+
+	keys, err := y.Keys("spec")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal([]string{"zeta", "alpha", "mid"}, keys)
+	keys[0] = "changed"
+	keys, _ = y.Keys("")
+	suite.Assert().Equal([]string{"base", "spec", "list"}, keys)
+
+	entries, err := y.Entries("spec")
+	suite.Assert().Nil(err)
+	suite.Assert().Len(entries, 3)
+	suite.Assert().Equal("alpha", entries[1].Key)
+	suite.Assert().Equal(yaml.SingleQuotedStyle, entries[1].KeyStyle)
+	suite.Assert().Equal(1, entries[1].Node.GetValue("x"))
+	suite.Assert().Equal(yaml.Style(0), entries[2].KeyStyle)
+
+	for _, path := range []string{"list", "spec.zeta"} {
+		_, err = y.Entries(path)
+		suite.Assert().ErrorIs(err, ErrInvalidType)
+	}
+	_, err = y.Keys("missing")
+	suite.Assert().ErrorIs(err, ErrNotFound)
+
+	visited := []string{}
+	err = y.Range("spec", func(key string, node *YamlWalker) bool {
+		visited = append(visited, key)
+		suite.Assert().Nil(y.Delete("spec." + key))
+		return key != "alpha"
+	})
+	suite.Assert().Nil(err)
+	suite.Assert().Equal([]string{"zeta", "alpha"}, visited)
+	keys, _ = y.Keys("spec")
+	suite.Assert().Equal([]string{"mid"}, keys)
+	data, err := yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("base: &b {x: 1}\nspec:\n    mid: [1]\nlist: [1]\n", string(data))
+}
+
+func (suite *YamlWalkerTestSuite) TestKeyOrder() {
+	parse := func(body string) *YamlWalker {
+		y := NewYamlWalker()
+		err := yaml.Unmarshal([]byte(body), y)
+		suite.Require().Nil(err)
+		return y
+	}
+	marshal := func(y *YamlWalker) string {
+		data, err := yaml.Marshal(y)
+		suite.Require().Nil(err)
+		return string(data)
+	}
+	source := `name: app
+# old name
+'oldKey': 1 # deprecated
+dependencies:
+    zlib: 1.2
+    curl: 7.0
+    b: {y: 1, x: 2}
+    list:
+        - {d: 1, c: 2}
+`
+
+	y := parse(source)
+	suite.Assert().Nil(y.RenameKey("oldKey", "newKey"))
+	suite.Assert().ErrorIs(y.RenameKey("newKey", "name"), ErrDuplicateKey)
+	suite.Assert().ErrorIs(y.RenameKey("missing", "other"), ErrNotFound)
+	suite.Assert().ErrorIs(y.RenameKey("name.x", "other"), ErrInvalidType)
+	suite.Assert().Nil(y.RenameKey("name", "name"))
+	suite.Assert().Equal(1, y.GetValue("newKey"))
+	suite.Assert().Nil(y.GetValue("oldKey"))
+	suite.Assert().Equal("name: app\n# old name\n'newKey': 1 # deprecated\n", marshal(y)[:46])
+
+	suite.Assert().Nil(y.MoveKey("name", KeyAfter, "dependencies"))
+	suite.Assert().Nil(y.MoveKey("dependencies.list", KeyBefore, "zlib"))
+	suite.Assert().Nil(y.MoveKey("dependencies.b", KeyAfter, "b"))
+	suite.Assert().ErrorIs(y.MoveKey("name", KeyAfter, "missing"), ErrNotFound)
+	keys, _ := y.Keys("")
+	suite.Assert().Equal([]string{"newKey", "dependencies", "name"}, keys)
+	keys, _ = y.Keys("dependencies")
+	suite.Assert().Equal([]string{"list", "zlib", "curl", "b"}, keys)
+
+	version := NewYamlWalker()
+	version.Update("1.0")
+	suite.Assert().Nil(y.InsertKeyAt("version", 1, version, yaml.DoubleQuotedStyle))
+	suite.Assert().ErrorIs(y.InsertKeyAt("version", 0, version), ErrDuplicateKey)
+	suite.Assert().ErrorIs(y.InsertKeyAt("other", 5, version), ErrInvalidRange)
+	suite.Assert().ErrorIs(y.InsertKeyAt("name.other", 0, version), ErrInvalidType)
+	keys, _ = y.Keys("")
+	suite.Assert().Equal([]string{"newKey", "version", "dependencies", "name"}, keys)
+
+	suite.Assert().Nil(y.SortKeys("dependencies", false, nil))
+	keys, _ = y.Keys("dependencies")
+	suite.Assert().Equal([]string{"b", "curl", "list", "zlib"}, keys)
+	keys, _ = y.Keys("dependencies.b")
+	suite.Assert().Equal([]string{"y", "x"}, keys)
+	suite.Assert().ErrorIs(y.SortKeys("name", false, nil), ErrInvalidType)
+
+	suite.Assert().Nil(y.SortKeys("", true, func(a, b string) bool { return a > b }))
+	suite.Assert().Equal(`"version": "1.0"
+# old name
+'newKey': 1 # deprecated
+name: app
+dependencies:
+    zlib: 1.2
+    list:
+        - {d: 1, c: 2}
+    curl: 7.0
+    b: {y: 1, x: 2}
+`, marshal(y))
+}
+
+func (suite *YamlWalkerTestSuite) TestDuplicateKeys() {
+	source := "a: 1\nb:\n  x: 1\n  x: 2\n# repeated\na: [3]\nc: 4\na: 5\n"
+
+	tests := []struct {
+		name     string
+		policy   DuplicateKeyPolicy
+		expected string
+		value    interface{}
+	}{
+		{
+			name:     "first wins",
+			policy:   DuplicateKeyFirstWins,
+			expected: "a: 1\nb:\n    x: 1\nc: 4\n",
+			value:    1,
+		},
+		{
+			name:     "last wins",
+			policy:   DuplicateKeyLastWins,
+			expected: "b:\n    x: 2\nc: 4\na: 5\n",
+			value:    5,
+		},
+		{
+			name:     "keep all",
+			policy:   DuplicateKeyKeepAll,
+			expected: "a: 1\nb:\n    x: 1\n    x: 2\n# repeated\na: [3]\nc: 4\na: 5\n",
+			value:    1,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		suite.Run(tc.name, func() {
+			y := NewYamlWalker()
+			y.SetDuplicateKeyPolicy(tc.policy)
+			err := yaml.Unmarshal([]byte(source), y)
+			suite.Require().Nil(err)
+			suite.Assert().Equal(tc.value, y.GetValue("a"))
+			keys, err := y.Keys("")
+			suite.Assert().Nil(err)
+			suite.Assert().Len(keys, 3)
+
+			data, err := yaml.Marshal(y)
+			suite.Assert().Nil(err)
+			suite.Assert().Equal(tc.expected, string(data))
+
+			data, err = yaml.Marshal(y.Clone())
+			suite.Assert().Nil(err)
+			suite.Assert().Equal(tc.expected, string(data))
+		})
+	}
+
+	// repeated keys belong to the first key of the same name, not to the key preceding them
+	keepAll := func() *YamlWalker {
+		y := NewYamlWalker()
+		y.SetDuplicateKeyPolicy(DuplicateKeyKeepAll)
+		err := yaml.Unmarshal([]byte("a: 1\nb: 2\na: 3\n"), y)
+		suite.Require().Nil(err)
+		return y
+	}
+	edits := []struct {
+		name     string
+		edit     func(y *YamlWalker) error
+		expected string
+	}{
+		{name: "delete preceding", edit: func(y *YamlWalker) error { return y.Delete("b") }, expected: "a: 1\na: 3\n"},
+		{name: "delete first", edit: func(y *YamlWalker) error { return y.Delete("a") }, expected: "b: 2\n"},
+		{name: "move preceding", edit: func(y *YamlWalker) error { return y.MoveKey("b", KeyBefore, "a") }, expected: "b: 2\na: 1\na: 3\n"},
+		{name: "sort", edit: func(y *YamlWalker) error { return y.SortKeys("", false, func(a, b string) bool { return a > b }) }, expected: "b: 2\na: 1\na: 3\n"},
+		{name: "sort ascending", edit: func(y *YamlWalker) error { return y.SortKeys("", false, nil) }, expected: "a: 1\na: 3\nb: 2\n"},
+		{name: "rename first", edit: func(y *YamlWalker) error { return y.RenameKey("a", "z") }, expected: "z: 1\nb: 2\nz: 3\n"},
+	}
+	for _, tc := range edits {
+		y := keepAll()
+		suite.Assert().Nil(tc.edit(y), tc.name)
+		data, err := yaml.Marshal(y)
+		suite.Assert().Nil(err)
+		suite.Assert().Equal(tc.expected, string(data), tc.name)
+	}
+
+	y := NewYamlWalker()
+	err := yaml.Unmarshal(numKeysFile, y)
+	suite.Assert().ErrorIs(err, ErrDuplicateKey)
+	suite.Assert().EqualError(err, "line 4: duplicate key name '1', first defined at line 1")
+
+	_, err = DecodeAll(bytes.NewReader([]byte("a: 1\n---\nb: 1\nb: 2\n")))
+	suite.Assert().ErrorIs(err, ErrDuplicateKey)
+	stream, err := DecodeAll(bytes.NewReader([]byte("a: 1\n---\nb: 1\nb: 2\n")), DuplicateKeyLastWins)
+	suite.Assert().Nil(err)
+	document, err := stream.Document(1)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(2, document.GetValue("b"))
+}
+
+func (suite *YamlWalkerTestSuite) TestTypedKeys() {
+	source := "1: one\n'1': str\n? [a, b]\n: seq\ntrue: yes\n2.5: half\n"
+
+	y := NewYamlWalker()
+	err := yaml.Unmarshal([]byte(source), y)
+	suite.Require().Nil(err)
+
+	data, err := yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(source, string(data))
+
+	keys, err := y.Keys("")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal([]string{"!!int 1", "1", "[a, b]", "true", "2.5"}, keys)
+
+	suite.Assert().Equal("str", y.GetValue("1"))
+	suite.Assert().Equal("one", y.GetValue(`"!!int 1"`))
+	suite.Assert().Equal("seq", y.GetValue(`"[a, b]"`))
+	suite.Assert().Equal("yes", y.GetValue("true"))
+
+	tests := []struct {
+		key      interface{}
+		expected string
+	}{
+		{key: 1, expected: "one"},
+		{key: "1", expected: "str"},
+		{key: true, expected: "yes"},
+		{key: 2.5, expected: "half"},
+	}
+	for _, tc := range tests {
+		node, err := y.GetByKey("", tc.key)
+		suite.Assert().Nil(err, tc.key)
+		if suite.Assert().NotNil(node) {
+			suite.Assert().Equal(tc.expected, node.Value(), tc.key)
+		}
+	}
+
+	seq := NewYamlWalker()
+	err = yaml.Unmarshal([]byte("[a, b]"), seq)
+	suite.Require().Nil(err)
+	node, err := y.GetByKey("", seq)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("seq", node.Value())
+
+	_, err = y.GetByKey("", false)
+	suite.Assert().ErrorIs(err, ErrNotFound)
+	_, err = y.GetByKey("1", 1)
+	suite.Assert().ErrorIs(err, ErrInvalidType)
+
+	entries, err := y.Entries("")
+	suite.Assert().Nil(err)
+	suite.Assert().Nil(entries[1].KeyNode)
+	suite.Assert().Equal(1, entries[0].KeyNode.Value())
+	suite.Assert().Len(entries[2].KeyNode.Value(), 2)
+
+	data, err = yaml.Marshal(y.Clone())
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(source, string(data))
+
+	merge := "a: &a\n    x: 1\nb:\n    <<: *a\n    y: 2\n"
+	err = yaml.Unmarshal([]byte(merge), y)
+	suite.Require().Nil(err)
+	data, err = yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(merge, string(data))
+}
+
+func (suite *YamlWalkerTestSuite) TestKind() {
+	y := NewYamlWalker()
+	err := yaml.Unmarshal([]byte("a:\nb: ~\nc: null\nd: [~, 1]\ne: {x: }\nf: &f 2\ng: *f\n"), y)
+	suite.Require().Nil(err)
+
+	tests := []struct {
+		path     string
+		expected Kind
+	}{
+		{path: "", expected: KindMapping},
+		{path: "a", expected: KindNull},
+		{path: "b", expected: KindNull},
+		{path: "d", expected: KindSequence},
+		{path: "d.1", expected: KindScalar},
+		{path: "e.x", expected: KindNull},
+		{path: "f", expected: KindScalar},
+	}
+	for _, tc := range tests {
+		node, err := y.Get(tc.path)
+		suite.Assert().Nil(err)
+		suite.Assert().Equal(tc.expected, node.Kind(), tc.path)
+	}
+	m, err := y.AsMap("")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(KindAlias, m["g"].Kind())
+	suite.Assert().Equal("alias", m["g"].Kind().String())
+
+	data, err := yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("a:\nb: ~\nc: null\nd: [~, 1]\ne: {x: null}\nf: &f 2\ng: *f\n", string(data))
+
+	y.SetValue("b", nil)
+	y.SetValue("c", 1)
+	y.SetValue("c", nil)
+	data, err = yaml.Marshal(y)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("a:\nb: ~\nc: null\nd: [~, 1]\ne: {x: null}\nf: &f 2\ng: *f\n", string(data))
+
+	n := NewMapping()
+	suite.Assert().Equal(KindMapping, n.Kind())
+	suite.Assert().Nil(n.Append("name", NewScalar("yes", yaml.DoubleQuotedStyle)))
+	suite.Assert().Nil(n.Append("count", NewScalar(3, 0)))
+	suite.Assert().Nil(n.Append("items", NewSequence(NewScalar("a", 0), NewNull())))
+	suite.Assert().Nil(n.Append("empty", NewMapping()))
+	suite.Assert().Nil(n.Append("none", NewNull()))
+	suite.Assert().Equal(KindNull, NewNull().Kind())
+	suite.Assert().Equal(KindSequence, NewSequence().Kind())
+
+	data, err = yaml.Marshal(n)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("name: \"yes\"\ncount: 3\nitems:\n    - a\n    - null\nempty: {}\nnone: null\n", string(data))
+
+	data, err = yaml.Marshal(NewYamlWalker())
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("null\n", string(data))
+}
+
+func (suite *YamlWalkerTestSuite) TestSyncWalker() {
+	y := NewYamlWalker()
+	err := yaml.Unmarshal([]byte("server:\n  host: localhost\n  port: 80\nitems: [a]\n"), y)
+	suite.Require().Nil(err)
+
+	s := NewSyncWalker(y)
+	y.SetValue("server.port", 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				s.SetValue("server.port", 8000+j)
+				suite.Assert().Nil(s.Append(fmt.Sprintf("server.key%d", i), NewScalar(j, 0)))
+				_ = s.Edit(func(root *YamlWalker) error {
+					if err := root.SetPath("server.host", fmt.Sprintf("host-%d", i)); err != nil {
+						return err
+					}
+					return root.Insert("items", 0, NewScalar(i, 0))
+				})
+				suite.Assert().Nil(s.Delete(fmt.Sprintf("server.key%d", i)))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				port, err := s.AsInt("server.port")
+				suite.Assert().Nil(err)
+				suite.Assert().GreaterOrEqual(port, 80)
+				snapshot := s.Snapshot()
+				host, err := snapshot.AsString("server.host")
+				suite.Assert().Nil(err)
+				suite.Assert().NotEmpty(host)
+				_, err = yaml.Marshal(s)
+				suite.Assert().Nil(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	port, err := s.AsInt("server.port")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(8049, port)
+	keys, err := s.Keys("server")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal([]string{"host", "port"}, keys)
+	items, err := s.Get("items")
+	suite.Assert().Nil(err)
+	suite.Assert().Len(items.Value(), 201)
+	suite.Assert().Equal(1, y.GetValue("server.port"))
+
+	// failed edit leaves the tree untouched
+	failure := errors.New("failure")
+	err = s.Edit(func(root *YamlWalker) error {
+		root.SetValue("server.port", 1)
+		return failure
+	})
+	suite.Assert().ErrorIs(err, failure)
+	suite.Assert().Equal(8049, s.GetValue("server.port"))
+
+	// returned nodes are copies
+	node, err := s.Get("server")
+	suite.Assert().Nil(err)
+	node.SetValue("port", 1)
+	suite.Assert().Equal(8049, s.GetValue("server.port"))
+
+	err = yaml.Unmarshal([]byte("a: 1\n"), s)
+	suite.Assert().Nil(err)
+	keys, err = s.Keys("")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal([]string{"a"}, keys)
+
+	// the zero value is the empty tree
+	var zero SyncWalker
+	suite.Assert().Nil(zero.GetValue("a"))
+	_, err = zero.Get("a")
+	suite.Assert().ErrorIs(err, ErrInvalidType)
+	data, err := yaml.Marshal(&zero)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("null\n", string(data))
+	suite.Assert().Nil(zero.SetPath("a.b", 1))
+	suite.Assert().Equal(1, zero.GetValue("a.b"))
+}
+
+func (suite *YamlWalkerTestSuite) TestPersistentWalker() {
+	source := "base: &base\n  port: 80\n  tags: [a, b]\nservice:\n  <<: *base\n  name: web\nother: *base\nlist:\n  - x: 1\n  - y: 2\n"
+	y := NewYamlWalker()
+	err := yaml.Unmarshal([]byte(source), y)
+	suite.Require().Nil(err)
+
+	base := NewPersistentWalker(y)
+
+	v1, err := base.SetValue("service.name", "api")
+	suite.Require().Nil(err)
+	suite.Assert().Equal("web", base.GetValue("service.name"))
+	suite.Assert().Equal("api", v1.GetValue("service.name"))
+
+	// untouched subtrees are shared
+	a, err := base.Get("list")
+	suite.Assert().Nil(err)
+	b, err := v1.Get("list")
+	suite.Assert().Nil(err)
+	suite.Assert().Same(a, b)
+	a, err = base.Get("service")
+	suite.Assert().Nil(err)
+	b, err = v1.Get("service")
+	suite.Assert().Nil(err)
+	suite.Assert().NotSame(a, b)
+
+	// aliases follow the changed anchored node
+	v2, err := v1.SetValue("base.port", 8080)
+	suite.Require().Nil(err)
+	suite.Assert().Equal(8080, v2.GetValue("other.port"))
+	suite.Assert().Equal(80, v1.GetValue("other.port"))
+	data, err := yaml.Marshal(v2)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("base: &base\n    port: 8080\n    tags: [a, b]\nservice:\n    <<: *base\n    name: api\nother: *base\nlist:\n    - x: 1\n    - y: 2\n", string(data))
+
+	// changes through the alias do not change the anchored node
+	v3, err := v2.SetValue("other.port", 1)
+	suite.Require().Nil(err)
+	suite.Assert().Equal(1, v3.GetValue("other.port"))
+	suite.Assert().Equal(8080, v3.GetValue("base.port"))
+	suite.Assert().Equal(8080, v2.GetValue("other.port"))
+
+	v4, err := v3.Append("list.2", NewScalar("z", 0))
+	suite.Require().Nil(err)
+	v4, err = v4.Insert("base.tags", 0, NewScalar("first", 0))
+	suite.Require().Nil(err)
+	v4, err = v4.Remove("list", 0)
+	suite.Require().Nil(err)
+	v4, err = v4.Delete("service")
+	suite.Require().Nil(err)
+	v4, err = v4.Append("service", NewNull())
+	suite.Require().Nil(err)
+	data, err = yaml.Marshal(v4)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("base: &base\n    port: 8080\n    tags: [first, a, b]\nother:\n    port: 1\n    tags: [a, b]\nlist:\n    - y: 2\n    - z\nservice: null\n", string(data))
+
+	data, err = yaml.Marshal(v2)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("base: &base\n    port: 8080\n    tags: [a, b]\nservice:\n    <<: *base\n    name: api\nother: *base\nlist:\n    - x: 1\n    - y: 2\n", string(data))
+	data, err = yaml.Marshal(base)
+	suite.Assert().Nil(err)
+	suite.Assert().Equal("base: &base\n    port: 80\n    tags: [a, b]\nservice:\n    <<: *base\n    name: web\nother: *base\nlist:\n    - x: 1\n    - y: 2\n", string(data))
+
+	_, err = base.SetValue("missing.key", 1)
+	suite.Assert().ErrorIs(err, ErrNotFound)
+	_, err = base.Remove("list", 5)
+	suite.Assert().ErrorIs(err, ErrInvalidRange)
+	_, err = base.Append("service.name", NewNull())
+	suite.Assert().ErrorIs(err, ErrDuplicateKey)
+
+	w := v1.Walker()
+	w.SetValue("service.name", "changed")
+	suite.Assert().Equal("api", v1.GetValue("service.name"))
+}
+
 func (suite *YamlWalkerTestSuite) TestSet() {
 	y := &YamlWalker{
 		data: map[string]*YamlWalker{